@@ -1,8 +1,10 @@
 // Package auth provides authentication functionality for the NeatLogic API.
-// It handles user login, password encryption, and JWT token management.
+// It handles user login, password encryption, and JWT token management, and provides
+// the Authenticator implementations selectable in config.yml: password, token and accesskey.
 package auth
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
@@ -33,28 +35,84 @@ type LoginResponse struct {
 	JwtToken string `json:"JwtToken"`
 }
 
-// Login performs authentication with the NeatLogic API using default configuration.
-// It encrypts the password based on the configuration and returns a JWT token on success.
+// Login performs authentication with the NeatLogic API using the given configuration.
+// It resolves secret references of the credentials, encrypts the password based on the
+// configuration and returns a JWT token on success.
+//
+// Parameters:
+//   - config: Configuration holding the NeatLogic endpoint and credentials
+//
+// Returns:
+//   - string: The JWT token if authentication is successful
+//   - error: An error if authentication fails
+func Login(config common.Configs) (string, error) {
+	return LoginCtx(context.Background(), config)
+}
+
+// LoginCtx performs authentication with the NeatLogic API using the given configuration.
+// Secret references of the password are resolved (see common.ResolveSecretsCtx), so config
+// can be passed as loaded by common.LoadConfig; config itself is not modified.
+// The TLS settings of the configuration are applied to the login request.
+// The login request is bound to ctx, so it is aborted when ctx is cancelled or its deadline expires.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of secret resolution and the login request
+//   - config: Configuration holding the NeatLogic endpoint and credentials
+//
+// Returns:
+//   - string: The JWT token if authentication is successful
+//   - error: An error if a secret cannot be resolved or authentication fails
+func LoginCtx(ctx context.Context, config common.Configs) (string, error) {
+	credentials := config.Global.Auth
+	if err := common.ResolveSecretsCtx(ctx, &credentials); err != nil {
+		return "", err
+	}
+	client, err := config.Global.Neatlogic.HTTPClient()
+	if err != nil {
+		return "", err
+	}
+	return LoginWithCredentialsCtx(ctx, client, config.Global.Neatlogic.Uri(), credentials)
+}
+
+// LoginWithCredentialsCtx performs authentication against the given NeatLogic base URL
+// using explicit credentials instead of the loaded configuration.
+// The credentials are used as given: secret references must be resolved first.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the login request
+//   - client: HTTP client used for the login request; http.DefaultClient is used if nil
+//   - neatlogicUri: Base URL of the NeatLogic API, including the tenant
+//   - credentials: Username, password and encryption method to log in with
 //
 // Returns:
 //   - string: The JWT token if authentication is successful
 //   - error: An error if authentication fails
-func Login() (string, error) {
-	api_login := fmt.Sprintf("%s/login/check", common.NeatlogicUri)
+func LoginWithCredentialsCtx(ctx context.Context, client *http.Client, neatlogicUri string, credentials common.Auth) (string, error) {
+	if client == nil {
+		// Avoid passing a typed-nil *http.Client through the Doer interface
+		return login(ctx, nil, neatlogicUri, credentials)
+	}
+	return login(ctx, client, neatlogicUri, credentials)
+}
+
+// login performs the password login shared by the Login functions and PasswordAuth.
+// A nil client means http.DefaultClient.
+func login(ctx context.Context, client Doer, neatlogicUri string, credentials common.Auth) (string, error) {
+	api_login := fmt.Sprintf("%s/login/check", neatlogicUri)
 	var JwtToken, encryptedPass string
 	// Password encryption
-	if common.Config.Global.Auth.Encrypt == "base64" {
-		encryptedPass = "{BS}" + base64.StdEncoding.EncodeToString([]byte(common.Config.Global.Auth.Password))
+	if credentials.Encrypt == "base64" {
+		encryptedPass = "{BS}" + base64.StdEncoding.EncodeToString([]byte(credentials.Password))
 	} else {
 		// Default to MD5
 		hasher := md5.New()
-		hasher.Write([]byte(common.Config.Global.Auth.Password))
+		hasher.Write([]byte(credentials.Password))
 		encryptedPass = "{MD5}" + hex.EncodeToString(hasher.Sum(nil))
 	}
 
 	// Create request body
 	reqBody := LoginRequest{
-		UserID:   common.Config.Global.Auth.Username,
+		UserID:   credentials.Username,
 		Password: encryptedPass,
 	}
 
@@ -65,9 +123,20 @@ func Login() (string, error) {
 	}
 
 	// Make HTTP request
-	resp, err := http.Post(api_login, "application/json", strings.NewReader(string(jsonData)))
+	req, err := http.NewRequestWithContext(ctx, "POST", api_login, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return "", errors.New("failed to create login request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
 	if err != nil {
-		return "", errors.New("failed to login")
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("failed to login: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -93,7 +162,7 @@ func Login() (string, error) {
 }
 
 // LoginWithConfigPath performs authentication with the NeatLogic API using a custom configuration file.
-// It initializes the configuration from the specified path, encrypts the password based on the configuration,
+// It loads the configuration from the specified path, encrypts the password based on the configuration,
 // and returns a JWT token on success.
 //
 // Parameters:
@@ -101,57 +170,26 @@ func Login() (string, error) {
 //
 // Returns:
 //   - string: The JWT token if authentication is successful
-//   - error: An error if authentication fails
+//   - error: An error if the configuration cannot be loaded or authentication fails
 func LoginWithConfigPath(configPath string) (string, error) {
-	common.InitWithConfigPath(configPath)
-	api_login := fmt.Sprintf("%s/login/check", common.NeatlogicUri)
-	var JwtToken, encryptedPass string
-	// Password encryption
-	if common.Config.Global.Auth.Encrypt == "base64" {
-		encryptedPass = "{BS}" + base64.StdEncoding.EncodeToString([]byte(common.Config.Global.Auth.Password))
-	} else {
-		// Default to MD5
-		hasher := md5.New()
-		hasher.Write([]byte(common.Config.Global.Auth.Password))
-		encryptedPass = "{MD5}" + hex.EncodeToString(hasher.Sum(nil))
-	}
-
-	// Create request body
-	reqBody := LoginRequest{
-		UserID:   common.Config.Global.Auth.Username,
-		Password: encryptedPass,
-	}
-
-	// Marshal to JSON
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", errors.New("failed to marshal login request")
-	}
-
-	// Make HTTP request
-	resp, err := http.Post(api_login, "application/json", strings.NewReader(string(jsonData)))
-	if err != nil {
-		return "", errors.New("failed to login")
-	}
-	defer resp.Body.Close()
+	return LoginWithConfigPathCtx(context.Background(), configPath)
+}
 
-	// Parse response
-	var loginResp LoginResponse
-	err = json.NewDecoder(resp.Body).Decode(&loginResp)
+// LoginWithConfigPathCtx performs authentication with the NeatLogic API using a custom configuration file.
+// Secret references of the password are resolved (see common.ResolveSecretsCtx).
+// The login request is bound to ctx, so it is aborted when ctx is cancelled or its deadline expires.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of secret resolution and the login request
+//   - configPath: Path to the configuration file to use
+//
+// Returns:
+//   - string: The JWT token if authentication is successful
+//   - error: An error if the configuration cannot be loaded or authentication fails
+func LoginWithConfigPathCtx(ctx context.Context, configPath string) (string, error) {
+	config, err := common.LoadConfig(configPath)
 	if err != nil {
-		return "", errors.New("failed to parse login response")
-	}
-
-	// Handle response
-	if loginResp.Status == "OK" {
-		// Store JWT token
-		if loginResp.JwtToken != "" {
-			JwtToken = loginResp.JwtToken
-		}
-		return JwtToken, nil
-	} else if loginResp.Status == "ERROR" {
-		return "", errors.New(loginResp.Message)
+		return "", err
 	}
-
-	return "", errors.New("unknown authentication error")
+	return LoginCtx(ctx, config)
 }
@@ -0,0 +1,67 @@
+package auth_test
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/hejingwen098/neatapi/auth"
+	"github.com/hejingwen098/neatapi/common"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+// serverConfig returns a configuration for srv logging in as DefaultUsername with password.
+func serverConfig(t *testing.T, srv *neatlogictest.Server, password string) common.Configs {
+	t.Helper()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config common.Configs
+	config.Global.Neatlogic = common.Neatlogic{Host: u.Hostname(), Port: port, Tenant: srv.Tenant}
+	config.Global.Auth = common.Auth{Username: neatlogictest.DefaultUsername, Password: password}
+	return config
+}
+
+func TestLoginResolvesSecrets(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	t.Setenv("NEATAPI_TEST_PASSWORD", neatlogictest.DefaultPassword)
+	secret := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(secret, []byte(neatlogictest.DefaultPassword+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, password := range []string{"env:NEATAPI_TEST_PASSWORD", "file:" + secret, neatlogictest.DefaultPassword} {
+		config := serverConfig(t, srv, password)
+		token, err := auth.Login(config)
+		if err != nil {
+			t.Errorf("password %s: %v", password, err)
+			continue
+		}
+		if token == "" {
+			t.Errorf("password %s: empty token", password)
+		}
+		if config.Global.Auth.Password != password {
+			t.Errorf("config password changed to %q", config.Global.Auth.Password)
+		}
+	}
+}
+
+func TestLoginUnresolvedSecret(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+
+	if _, err := auth.Login(serverConfig(t, srv, "env:NEATAPI_TEST_UNSET")); err == nil {
+		t.Fatal("login with an unset environment variable succeeded")
+	}
+	if got := srv.Requests(neatlogictest.PathLogin); got != 0 {
+		t.Errorf("logins = %d, want 0", got)
+	}
+}
@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hejingwen098/neatapi/common"
+)
+
+// Authentication types, selected by the type key of the auth section in config.yml.
+const (
+	// TypePassword logs in with username and password and sends the JWT token as bearer token.
+	TypePassword = "password"
+	// TypeToken sends a pre-obtained token as bearer token.
+	TypeToken = "token"
+	// TypeAccessKey signs every request with an access key and secret (HMAC-SHA256).
+	TypeAccessKey = "accesskey"
+)
+
+// Doer sends HTTP requests. *http.Client satisfies it.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Authenticator authenticates requests to the NeatLogic API.
+// Implementations must be safe for concurrent use.
+type Authenticator interface {
+	// Authenticate adds credentials to req right before it is sent.
+	Authenticate(ctx context.Context, req *http.Request) error
+	// Refresh is called when the server rejects, with 401 Unauthorized, a request prepared by
+	// Authenticate; rejected is that request. It reports whether the request should be sent again.
+	Refresh(ctx context.Context, rejected *http.Request) (bool, error)
+}
+
+// NewAuthenticator creates the Authenticator selected by config.Type.
+// An empty type means password authentication if a username is configured,
+// and token authentication otherwise. The secrets of config are used as given:
+// references such as env: or file: must be resolved first, e.g. with common.ResolveSecretsCtx.
+//
+// Parameters:
+//   - client: Client used for login requests; http.DefaultClient is used if nil
+//   - neatlogicUri: Base URL of the NeatLogic API, including the tenant
+//   - config: Authentication configuration
+//
+// Returns:
+//   - Authenticator: The configured authenticator
+//   - error: An error if the type is unknown or its settings are missing
+func NewAuthenticator(client Doer, neatlogicUri string, config common.Auth) (Authenticator, error) {
+	switch config.Method() {
+	case TypePassword:
+		if config.Username == "" {
+			return nil, errors.New("neither credentials nor token are configured")
+		}
+		auth := NewPasswordAuth(client, neatlogicUri, config)
+		auth.SetToken(config.Token)
+		if config.TokenCache != "" {
+			auth.SetTokenCache(NewTokenCache(config.TokenCache))
+		}
+		return auth, nil
+	case TypeToken:
+		if config.Token == "" {
+			return nil, errors.New("auth type token requires a token")
+		}
+		return TokenAuth{Token: config.Token}, nil
+	case TypeAccessKey:
+		if config.AccessKey == "" || config.SecretKey == "" {
+			return nil, errors.New("auth type accesskey requires an access key and a secret key")
+		}
+		return AccessKeyAuth{AccessKey: config.AccessKey, SecretKey: config.SecretKey}, nil
+	}
+	return nil, fmt.Errorf("unknown auth type %q", config.Type)
+}
+
+// PasswordAuth authenticates requests with a JWT token obtained by logging in with
+// username and password. When the server rejects the token, it logs in again;
+// concurrent callers share a single login.
+type PasswordAuth struct {
+	client       Doer
+	neatlogicUri string
+	credentials  common.Auth
+	cache        *TokenCache
+
+	// mu guards token and serializes logins.
+	mu    sync.Mutex
+	token string
+}
+
+// NewPasswordAuth creates a password authenticator. It does not log in until Login is called
+// or the first request is authenticated. Tokens are not cached on disk unless SetTokenCache is called.
+//
+// Parameters:
+//   - client: Client used for login requests; http.DefaultClient is used if nil
+//   - neatlogicUri: Base URL of the NeatLogic API, including the tenant
+//   - credentials: Username, password and encryption method to log in with
+//
+// Returns:
+//   - *PasswordAuth: The authenticator
+func NewPasswordAuth(client Doer, neatlogicUri string, credentials common.Auth) *PasswordAuth {
+	return &PasswordAuth{
+		client:       client,
+		neatlogicUri: neatlogicUri,
+		credentials:  credentials,
+	}
+}
+
+// Login obtains a new token and replaces the current one. With a token cache,
+// a cached token that is not about to expire is used instead of logging in.
+func (a *PasswordAuth) Login(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.obtain(ctx, "")
+}
+
+// SetTokenCache makes the authenticator share tokens through cache with other processes.
+// A nil cache disables sharing.
+func (a *PasswordAuth) SetTokenCache(cache *TokenCache) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cache = cache
+}
+
+// Token returns the current JWT token, or an empty string before the first login.
+func (a *PasswordAuth) Token() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.token
+}
+
+// SetToken replaces the current token, e.g. with one obtained earlier, so that no login is needed
+// until the server rejects it.
+func (a *PasswordAuth) SetToken(token string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = token
+}
+
+// Authenticate sets the bearer token of req, logging in first if there is no token yet.
+func (a *PasswordAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token == "" {
+		if err := a.obtain(ctx, ""); err != nil {
+			return err
+		}
+	}
+	setBearer(req, a.token)
+	return nil
+}
+
+// Refresh logs in again unless another request already replaced the token rejected was sent with.
+func (a *PasswordAuth) Refresh(ctx context.Context, rejected *http.Request) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if rejected.Header.Get("Authorization") != "Bearer "+a.token {
+		// Another request already refreshed the token while this one was in flight
+		return true, nil
+	}
+	if err := a.obtain(ctx, a.token); err != nil {
+		return false, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	return true, nil
+}
+
+// obtain replaces the token by logging in, or through the token cache if set.
+// stale is a token the server rejected. a.mu must be held.
+func (a *PasswordAuth) obtain(ctx context.Context, stale string) error {
+	passwordLogin := func(ctx context.Context) (string, error) {
+		return login(ctx, a.client, a.neatlogicUri, a.credentials)
+	}
+	var token string
+	var err error
+	if a.cache != nil {
+		token, err = a.cache.Token(ctx, a.neatlogicUri, a.credentials.Username, stale, passwordLogin)
+	} else {
+		token, err = passwordLogin(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	a.token = token
+	return nil
+}
+
+// TokenAuth authenticates requests with a fixed bearer token, e.g. one issued to a service account.
+type TokenAuth struct {
+	// Token is the bearer token.
+	Token string
+}
+
+// Authenticate sets the bearer token of req.
+func (a TokenAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	setBearer(req, a.Token)
+	return nil
+}
+
+// Refresh reports that a rejected token cannot be renewed.
+func (a TokenAuth) Refresh(ctx context.Context, rejected *http.Request) (bool, error) {
+	return false, nil
+}
+
+// AccessKeyAuth signs requests with a NeatLogic access key and secret.
+// The signature is the hex HMAC-SHA256, keyed with the secret, of
+//
+//	accessKey#path#timestamp[#base64(body)]
+//
+// where timestamp is the x-access-date header in milliseconds since the Unix epoch.
+type AccessKeyAuth struct {
+	// AccessKey identifies the account, sent in the x-access-key header.
+	AccessKey string
+	// SecretKey is the shared secret the signature is keyed with. It is never sent.
+	SecretKey string
+}
+
+// Authenticate signs req.
+func (a AccessKeyAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	body, err := peekBody(req)
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	content := a.AccessKey + "#" + req.URL.Path + "#" + timestamp
+	if len(body) > 0 {
+		content += "#" + base64.StdEncoding.EncodeToString(body)
+	}
+	mac := hmac.New(sha256.New, []byte(a.SecretKey))
+	mac.Write([]byte(content))
+
+	req.Header.Set("AuthType", "hmac")
+	req.Header.Set("x-access-key", a.AccessKey)
+	req.Header.Set("x-access-date", timestamp)
+	req.Header.Set("Authorization", "Hmac "+hex.EncodeToString(mac.Sum(nil)))
+	return nil
+}
+
+// Refresh reports that a rejected signature cannot be renewed.
+func (a AccessKeyAuth) Refresh(ctx context.Context, rejected *http.Request) (bool, error) {
+	return false, nil
+}
+
+// setBearer sets the Authorization header of req to a bearer token.
+func setBearer(req *http.Request, token string) {
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
+// peekBody returns the body of req without consuming it.
+func peekBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return data, nil
+}
@@ -0,0 +1,192 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hejingwen098/neatapi/auth"
+	"github.com/hejingwen098/neatapi/common"
+	"github.com/hejingwen098/neatapi/neatlogic"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  common.Auth
+		want    string
+		wantErr bool
+	}{
+		{"password by default", common.Auth{Username: "admin", Password: "secret"}, "*auth.PasswordAuth", false},
+		{"token by default", common.Auth{Token: "abc"}, "auth.TokenAuth", false},
+		{"accesskey", common.Auth{Type: "AccessKey", AccessKey: "ak", SecretKey: "sk"}, "auth.AccessKeyAuth", false},
+		{"nothing configured", common.Auth{}, "", true},
+		{"token without token", common.Auth{Type: auth.TypeToken}, "", true},
+		{"accesskey without secret", common.Auth{Type: auth.TypeAccessKey, AccessKey: "ak"}, "", true},
+		{"unknown type", common.Auth{Type: "kerberos", Username: "admin"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator, err := auth.NewAuthenticator(nil, "http://neatlogic/demo", tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %T, want an error", authenticator)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := typeName(authenticator); got != tt.want {
+				t.Errorf("type = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// typeName returns the dynamic type of an authenticator.
+func typeName(authenticator auth.Authenticator) string {
+	switch authenticator.(type) {
+	case *auth.PasswordAuth:
+		return "*auth.PasswordAuth"
+	case auth.TokenAuth:
+		return "auth.TokenAuth"
+	case auth.AccessKeyAuth:
+		return "auth.AccessKeyAuth"
+	}
+	return "unknown"
+}
+
+func TestPasswordAuthSharesRefresh(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	id := srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: "web-01"})
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.ExpireTokens()
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetCientity(1, id); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if got := srv.Requests(neatlogictest.PathLogin); got != 2 {
+		t.Errorf("logins = %d, want the initial one and a single refresh", got)
+	}
+}
+
+func TestPasswordAuthLogsInLazily(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	credentials := common.Auth{Username: neatlogictest.DefaultUsername, Password: neatlogictest.DefaultPassword}
+	password := auth.NewPasswordAuth(srv.Client(), srv.URL+"/"+srv.Tenant, credentials)
+	if password.Token() != "" {
+		t.Fatal("token set before login")
+	}
+	client, err := srv.NewClient(neatlogic.WithAuthenticator(password))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logins := srv.Requests(neatlogictest.PathLogin)
+
+	srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: "web-01"})
+	if _, err := client.GetAllCientity(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Requests(neatlogictest.PathLogin) - logins; got != 1 {
+		t.Errorf("logins by the authenticator = %d, want 1", got)
+	}
+	if password.Token() == "" {
+		t.Error("token not set after the first request")
+	}
+}
+
+func TestTokenAuthDoesNotRefresh(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	client, err := srv.NewClient(neatlogic.WithAuthenticator(auth.TokenAuth{Token: "revoked"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logins := srv.Requests(neatlogictest.PathLogin)
+
+	if _, err := client.GetAllCientity(1); !errors.Is(err, neatlogic.ErrUnauthorized) {
+		t.Fatalf("err = %v, want ErrUnauthorized", err)
+	}
+	if got := srv.Requests(neatlogictest.PathCientitySearch); got != 1 {
+		t.Errorf("search requests = %d, want 1", got)
+	}
+	if got := srv.Requests(neatlogictest.PathLogin); got != logins {
+		t.Errorf("logins = %d, want %d", got, logins)
+	}
+}
+
+func TestAccessKeyAuthSignsRequests(t *testing.T) {
+	const accessKey, secretKey = "ci-bot", "s3cr3t"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		content := accessKey + "#" + r.URL.Path + "#" + r.Header.Get("x-access-date")
+		if len(body) > 0 {
+			content += "#" + base64.StdEncoding.EncodeToString(body)
+		}
+		mac := hmac.New(sha256.New, []byte(secretKey))
+		mac.Write([]byte(content))
+		if r.Header.Get("AuthType") != "hmac" || r.Header.Get("x-access-key") != accessKey ||
+			r.Header.Get("Authorization") != "Hmac "+hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"Status":"OK","Return":{"pageCount":1}}`))
+	}))
+	defer srv.Close()
+
+	client, err := neatlogic.New(
+		neatlogic.WithBaseURL(srv.URL),
+		neatlogic.WithTenant("demo"),
+		neatlogic.WithHTTPClient(srv.Client()),
+		neatlogic.WithAuthenticator(auth.AccessKeyAuth{AccessKey: accessKey, SecretKey: secretKey}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetAllCientity(1); err != nil {
+		t.Fatalf("signed request rejected: %v", err)
+	}
+
+	wrong := auth.AccessKeyAuth{AccessKey: accessKey, SecretKey: "wrong"}
+	req, _ := http.NewRequestWithContext(context.Background(), "POST", srv.URL+"/demo/api", strings.NewReader("{}"))
+	if err := wrong.Authenticate(req.Context(), req); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status with wrong secret = %d, want 401", resp.StatusCode)
+	}
+}
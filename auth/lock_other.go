@@ -0,0 +1,40 @@
+//go:build !unix || solaris || aix
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// staleLockAge is the age after which a lock file is considered left behind by a dead process.
+const staleLockAge = 30 * time.Second
+
+// lockFile is used where flock is not available. It takes an exclusive lock by creating
+// the file at path, which must not exist, and waits until it can or ctx is done.
+// The lock is released by the returned function.
+// A lock file older than staleLockAge is removed, so a crashed process cannot block others forever.
+func lockFile(ctx context.Context, path string) (func(), error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() {
+				os.Remove(path)
+			}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+		if err := waitLock(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
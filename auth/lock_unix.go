@@ -0,0 +1,38 @@
+//go:build unix && !solaris && !aix
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive flock on the file at path, creating it if missing,
+// and waits until the lock is free or ctx is done. The lock is released by the
+// returned function, or by the operating system if the process dies.
+func lockFile(ctx context.Context, path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) && !errors.Is(err, syscall.EINTR) {
+			f.Close()
+			return nil, err
+		}
+		if err := waitLock(ctx); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
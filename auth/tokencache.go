@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultTokenCacheSkew is how long before its expiry a cached token is no longer reused.
+const DefaultTokenCacheSkew = time.Minute
+
+// lockPollInterval is how often a busy cache lock is tried again.
+const lockPollInterval = 50 * time.Millisecond
+
+// TokenCache stores JWT tokens on disk so that processes logging in as the same user
+// to the same NeatLogic tenant share a token instead of each logging in.
+// Every host, tenant and user has its own file, readable only by its owner.
+// A lock file serializes processes, so only one of them logs in when the token expires.
+type TokenCache struct {
+	// Dir is the directory holding the cache files. It is created with mode 0700 if missing.
+	Dir string
+	// Skew is how long before its expiry a token is no longer reused.
+	// DefaultTokenCacheSkew is used if zero.
+	Skew time.Duration
+}
+
+// tokenCacheEntry is the content of a cache file.
+type tokenCacheEntry struct {
+	NeatlogicUri string `json:"neatlogicUri"`
+	Username     string `json:"username"`
+	Token        string `json:"token"`
+}
+
+// DefaultTokenCacheDir returns the directory neatapi caches tokens in by default,
+// below the user's cache directory (e.g. ~/.cache/neatapi/tokens on Linux).
+func DefaultTokenCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "neatapi", "tokens"), nil
+}
+
+// NewTokenCache creates a token cache in dir.
+func NewTokenCache(dir string) *TokenCache {
+	return &TokenCache{Dir: dir}
+}
+
+// Token returns the cached token of username at neatlogicUri, or obtains a new one with login
+// and caches it if there is none, it expires within Skew, or it equals stale.
+// The cache file is locked while the token is read and renewed.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation of the lock wait and the login
+//   - neatlogicUri: Base URL of the NeatLogic API, including the tenant
+//   - username: The user the token belongs to
+//   - stale: A token the server rejected and that must not be reused; empty if none
+//   - login: Function obtaining a new token
+//
+// Returns:
+//   - string: The token
+//   - error: An error if the cache cannot be locked or written, or login fails
+func (c *TokenCache) Token(ctx context.Context, neatlogicUri string, username string, stale string, login func(ctx context.Context) (string, error)) (string, error) {
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create token cache: %w", err)
+	}
+	path := c.path(neatlogicUri, username)
+	unlock, err := lockFile(ctx, path+".lock")
+	if err != nil {
+		return "", fmt.Errorf("failed to lock token cache: %w", err)
+	}
+	defer unlock()
+
+	if entry, err := readTokenCache(path); err == nil && entry.Token != "" && entry.Token != stale && c.fresh(entry.Token) {
+		return entry.Token, nil
+	}
+
+	token, err := login(ctx)
+	if err != nil {
+		return "", err
+	}
+	entry := tokenCacheEntry{NeatlogicUri: neatlogicUri, Username: username, Token: token}
+	if err := writeTokenCache(path, entry); err != nil {
+		return "", fmt.Errorf("failed to write token cache: %w", err)
+	}
+	return token, nil
+}
+
+// path returns the cache file of username at neatlogicUri.
+func (c *TokenCache) path(neatlogicUri, username string) string {
+	sum := sha256.Sum256([]byte(neatlogicUri + "\x00" + username))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// fresh reports whether token does not expire within the skew.
+// Tokens without a readable expiry are reused until the server rejects them.
+func (c *TokenCache) fresh(token string) bool {
+	expiry, ok := TokenExpiry(token)
+	if !ok {
+		return true
+	}
+	skew := c.Skew
+	if skew == 0 {
+		skew = DefaultTokenCacheSkew
+	}
+	return time.Now().Add(skew).Before(expiry)
+}
+
+// TokenExpiry returns the expiry of a JWT token, taken from the standard exp claim
+// or NeatLogic's expiretime claim (in milliseconds). The signature is not verified.
+//
+// Parameters:
+//   - token: The JWT token
+//
+// Returns:
+//   - time.Time: The expiry of the token
+//   - bool: False if the token is not a JWT or carries no expiry
+func TokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp        float64 `json:"exp"`
+		ExpireTime float64 `json:"expiretime"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, false
+	}
+	switch {
+	case claims.Exp > 0:
+		return time.Unix(int64(claims.Exp), 0), true
+	case claims.ExpireTime > 0:
+		return time.UnixMilli(int64(claims.ExpireTime)), true
+	}
+	return time.Time{}, false
+}
+
+// readTokenCache reads a cache file.
+func readTokenCache(path string) (tokenCacheEntry, error) {
+	var entry tokenCacheEntry
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entry, err
+	}
+	err = json.Unmarshal(data, &entry)
+	return entry, err
+}
+
+// writeTokenCache replaces a cache file atomically. The file is only readable by its owner.
+func writeTokenCache(path string, entry tokenCacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".token-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// waitLock waits before the next attempt to take a busy lock, or returns ctx.Err() if ctx is done first.
+func waitLock(ctx context.Context) error {
+	timer := time.NewTimer(lockPollInterval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
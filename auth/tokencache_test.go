@@ -0,0 +1,161 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hejingwen098/neatapi/auth"
+	"github.com/hejingwen098/neatapi/neatlogic"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+// jwt returns an unsigned JWT token with the given claims.
+func jwt(claims string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"none"}`)) + "." + encode([]byte(claims)) + ".sig"
+}
+
+func TestTokenCacheSharesLogin(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 5)
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := srv.NewClient(neatlogic.WithTokenCache(dir))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			tokens[i] = client.JwtToken
+		}()
+	}
+	wg.Wait()
+	if got := srv.Requests(neatlogictest.PathLogin); got != 1 {
+		t.Errorf("logins = %d, want 1", got)
+	}
+	for i, token := range tokens {
+		if token != tokens[0] {
+			t.Errorf("client %d token = %q, want %q", i, token, tokens[0])
+		}
+	}
+}
+
+func TestTokenCacheReplacesRejectedToken(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: "web-01"})
+	dir := t.TempDir()
+	first, err := srv.NewClient(neatlogic.WithTokenCache(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := srv.NewClient(neatlogic.WithTokenCache(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.ExpireTokens()
+	for _, client := range []*neatlogic.NeatClient{first, second} {
+		if _, err := client.GetAllCientity(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first client logs in again, the second one picks up its token from the cache
+	if got := srv.Requests(neatlogictest.PathLogin); got != 2 {
+		t.Errorf("logins = %d, want 2", got)
+	}
+}
+
+func TestTokenCacheFileModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+	dir := filepath.Join(t.TempDir(), "tokens")
+	cache := auth.NewTokenCache(dir)
+	login := func(ctx context.Context) (string, error) { return "token", nil }
+	if _, err := cache.Token(context.Background(), "http://neatlogic/demo", "admin", "", login); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o700 {
+		t.Errorf("directory mode = %v, want 0700", mode)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("cache files = %v (%v), want one", files, err)
+	}
+	info, err = os.Stat(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("file mode = %v, want 0600", mode)
+	}
+}
+
+func TestTokenCacheExpiry(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		cached string
+		reused bool
+	}{
+		{"valid", jwt(fmt.Sprintf(`{"exp":%d}`, now.Add(time.Hour).Unix())), true},
+		{"expiring within skew", jwt(fmt.Sprintf(`{"exp":%d}`, now.Add(30*time.Second).Unix())), false},
+		{"expired NeatLogic claim", jwt(fmt.Sprintf(`{"expiretime":%d}`, now.Add(-time.Minute).UnixMilli())), false},
+		{"no expiry", "opaque-token", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := auth.NewTokenCache(t.TempDir())
+			logins := 0
+			login := func(token string) func(ctx context.Context) (string, error) {
+				return func(ctx context.Context) (string, error) {
+					logins++
+					return token, nil
+				}
+			}
+			ctx := context.Background()
+			if _, err := cache.Token(ctx, "http://neatlogic/demo", "admin", "", login(tt.cached)); err != nil {
+				t.Fatal(err)
+			}
+			token, err := cache.Token(ctx, "http://neatlogic/demo", "admin", "", login("new"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reused := token == tt.cached; reused != tt.reused {
+				t.Errorf("reused = %v, want %v (logins %d)", reused, tt.reused, logins)
+			}
+		})
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	expiry, ok := auth.TokenExpiry(jwt(`{"exp":1700000000}`))
+	if !ok || !expiry.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("exp claim: %v %v", expiry, ok)
+	}
+	expiry, ok = auth.TokenExpiry(jwt(`{"expiretime":1700000000123}`))
+	if !ok || !expiry.Equal(time.UnixMilli(1700000000123)) {
+		t.Errorf("expiretime claim: %v %v", expiry, ok)
+	}
+	if _, ok := auth.TokenExpiry("not-a-jwt"); ok {
+		t.Error("opaque token has an expiry")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hejingwen098/neatapi/common"
+)
+
+// runConfig implements "neatapi config": helpers for storing secrets outside of config.yml.
+//
+//	neatapi config keygen   Create the encryption key file for enc: secrets
+//	neatapi config encrypt  Encrypt a secret read from standard input
+func runConfig(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: neatapi config keygen|encrypt [flags]")
+		return 2
+	}
+	switch args[0] {
+	case "keygen":
+		return runConfigKeygen(args[1:])
+	case "encrypt":
+		return runConfigEncrypt(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "Error: unknown config command %s\n", args[0])
+	return 2
+}
+
+// runConfigKeygen implements "neatapi config keygen": it writes a new random key file,
+// readable only by its owner. An existing key file is never replaced, as the secrets
+// encrypted with it could no longer be decrypted.
+func runConfigKeygen(args []string) int {
+	fs := flag.NewFlagSet("config keygen", flag.ExitOnError)
+	keyFile := keyFileFlag(fs)
+	fs.Parse(args)
+
+	path, err := keyFilePath(*keyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error locating key file: %v\n", err)
+		return 1
+	}
+	key, err := common.GenerateSecretKey()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating key: %v\n", err)
+		return 1
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating key directory: %v\n", err)
+		return 1
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		fmt.Fprintf(os.Stderr, "Error: key file %s already exists\n", path)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating key file: %v\n", err)
+		return 1
+	}
+	if _, err := f.Write(common.EncodeSecretKey(key)); err != nil {
+		f.Close()
+		fmt.Fprintf(os.Stderr, "Error writing key file: %v\n", err)
+		return 1
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing key file: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote key file %s\n", path)
+	return 0
+}
+
+// runConfigEncrypt implements "neatapi config encrypt": it reads a secret from the first line
+// of standard input and prints the enc: value to put into config.yml.
+func runConfigEncrypt(args []string) int {
+	fs := flag.NewFlagSet("config encrypt", flag.ExitOnError)
+	keyFile := keyFileFlag(fs)
+	fs.Parse(args)
+
+	path, err := keyFilePath(*keyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error locating key file: %v\n", err)
+		return 1
+	}
+	key, err := common.LoadSecretKey(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "Enter the secret, followed by a line break:")
+	secret, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && secret == "" {
+		fmt.Fprintf(os.Stderr, "Error reading secret: %v\n", err)
+		return 1
+	}
+	value, err := common.EncryptSecret(strings.TrimRight(secret, "\r\n"), key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encrypting secret: %v\n", err)
+		return 1
+	}
+	fmt.Println(value)
+	return 0
+}
+
+// keyFileFlag registers the -key-file flag on fs.
+func keyFileFlag(fs *flag.FlagSet) *string {
+	return fs.String("key-file", os.Getenv(common.EnvEncryptionKeyFile), "Encryption key file (defaults to $NEATLOGIC_ENCRYPTION_KEY_FILE, then ~/.config/neatapi/secret.key)")
+}
+
+// keyFilePath returns the key file to use, falling back to the default one.
+func keyFilePath(keyFile string) (string, error) {
+	if keyFile != "" {
+		return keyFile, nil
+	}
+	return common.DefaultSecretKeyFile()
+}
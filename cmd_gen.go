@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hejingwen098/neatapi/gen"
+	"github.com/hejingwen098/neatapi/neatlogic"
+)
+
+// runGen implements "neatapi gen": it reads CI models from NeatLogic and writes
+// tagged Go structs and typed query helpers for them.
+func runGen(args []string) int {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	newClient := clientFlags(fs)
+	ciNames := fs.String("ci", "", "Comma-separated names of the CIs to generate (default: all CIs)")
+	pkg := fs.String("pkg", "cmdb", "Package name of the generated file")
+	out := fs.String("out", "", "Output file (default: standard output)")
+	fs.Parse(args)
+
+	ctx := context.Background()
+	neatClient, err := newClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating client: %v\n", err)
+		return 1
+	}
+
+	// Resolve CI names, defaulting to every CI
+	all, err := neatClient.ListCisCtx(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing CIs: %v\n", err)
+		return 1
+	}
+	ciIds := map[string]int64{}
+	var names []string
+	for _, ci := range all {
+		ciIds[ci.Name] = ci.ID
+		names = append(names, ci.Name)
+	}
+	if *ciNames != "" {
+		names = strings.Split(*ciNames, ",")
+	}
+
+	var cis []neatlogic.Ci
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		ciId, ok := ciIds[name]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: CI %s not found\n", name)
+			return 1
+		}
+		ci, err := neatClient.GetCiCtx(ctx, ciId)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting CI %s: %v\n", name, err)
+			return 1
+		}
+		cis = append(cis, ci)
+	}
+
+	src, err := gen.Generate(*pkg, cis)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating code: %v\n", err)
+		return 1
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return 0
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", *out, err)
+		return 1
+	}
+	return 0
+}
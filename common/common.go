@@ -1,29 +1,40 @@
 // Package common provides common utilities and configuration management for the NeatAPI SDK.
-// It handles configuration loading, parsing, and global variable initialization.
+// It handles configuration loading and parsing. The package holds no global state:
+// every configuration is loaded explicitly and owned by its caller.
 package common
 
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
-var (
-	// Config holds the application configuration loaded from config.yml.
-	Config Configs
-	// NeatlogicUri is the base URL for the NeatLogic API, constructed from configuration.
-	NeatlogicUri string
-)
-
 // Auth represents the authentication configuration section.
 type Auth struct {
+	// Type selects the authentication method: password, token or accesskey.
+	// If empty, password is used when a username is set and token otherwise.
+	Type string `yaml:"type"`
 	// Username is the user identifier for authentication.
 	Username string `yaml:"username"`
-	// Password is the password for authentication.
+	// Password is the password for authentication. Like Token and SecretKey, it may refer to
+	// a secret stored elsewhere, e.g. env:NAME or file:/path (see ResolveSecretCtx).
 	Password string `yaml:"password"`
 	// Encrypt specifies the encryption method (base64 or md5).
 	Encrypt string `yaml:"encrypt"`
+	// Token is a pre-obtained bearer token. With password authentication it is used until it expires.
+	Token string `yaml:"token"`
+	// AccessKey identifies the account for access key authentication.
+	AccessKey string `yaml:"access_key"`
+	// SecretKey is the secret used to sign requests for access key authentication.
+	SecretKey string `yaml:"secret_key"`
+	// TokenCache is the directory of the on-disk token cache shared by processes using
+	// password authentication. The cache is disabled if empty.
+	TokenCache string `yaml:"token_cache"`
+	// EncryptionKeyFile is the key file used to decrypt enc: secrets.
+	// DefaultSecretKeyFile is used if empty.
+	EncryptionKeyFile string `yaml:"encryption_key_file"`
 }
 
 // Neatlogic represents the NeatLogic service configuration section.
@@ -34,6 +45,24 @@ type Neatlogic struct {
 	Port int `yaml:"port"`
 	// Tenant is the tenant identifier for the NeatLogic instance.
 	Tenant string `yaml:"tenant"`
+	// Scheme is the URL scheme of the NeatLogic server (http or https). Defaults to http.
+	Scheme string `yaml:"scheme"`
+	// BasePath is an optional path prefix in front of the tenant, e.g. when behind a reverse proxy.
+	BasePath string `yaml:"base_path"`
+	// TLS contains TLS settings used when Scheme is https.
+	TLS TLS `yaml:"tls"`
+}
+
+// TLS represents the TLS configuration of the NeatLogic endpoint.
+type TLS struct {
+	// CAFile is the path to a PEM bundle of additional CA certificates to trust.
+	CAFile string `yaml:"ca_file"`
+	// CertFile is the path to the PEM client certificate used for mTLS.
+	CertFile string `yaml:"cert_file"`
+	// KeyFile is the path to the PEM private key of the client certificate.
+	KeyFile string `yaml:"key_file"`
+	// InsecureSkipVerify disables server certificate verification. Only use it in lab setups.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
 }
 
 // Global represents the global configuration section.
@@ -47,54 +76,108 @@ type Global struct {
 // Configs represents the complete configuration structure.
 type Configs struct {
 	// Global contains global configuration settings.
+	// After a profile is selected it holds the effective settings of that profile.
 	Global Global `yaml:"global"`
+	// Profiles contains named configuration sections (e.g. prod, staging).
+	// A selected profile overrides the settings it defines in Global.
+	Profiles map[string]Global `yaml:"profiles"`
 }
 
-// init initializes the configuration when the package is loaded.
-// It reads the default config.yml file and parses the configuration.
-func init() {
-	// Initialize configuration file
-	Config = Configs{}
-	configFile, err := os.Open("./config.yml")
-	if err != nil {
-		fmt.Printf("Error opening config file: %s", err)
-		// return nil, err
+// Method returns the authentication method selected by the section in lower case:
+// Type if set, otherwise password when a username is set and token when only a token is.
+func (a Auth) Method() string {
+	if a.Type != "" {
+		return strings.ToLower(a.Type)
+	}
+	if a.Username == "" && a.Token != "" {
+		return "token"
 	}
-	defer configFile.Close()
+	return "password"
+}
 
-	// Parse configuration file
-	decoder := yaml.NewDecoder(configFile)
-	err = decoder.Decode(&Config)
-	if err != nil {
-		fmt.Printf("Error parsing config file: %s", err)
-		// return nil, err
+// Uri returns the base URL for the NeatLogic API described by the configuration section,
+// in the form scheme://host:port/basePath/tenant.
+func (n Neatlogic) Uri() string {
+	scheme := n.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	host := n.Host
+	if n.Port != 0 {
+		host = fmt.Sprintf("%s:%d", n.Host, n.Port)
 	}
-	NeatlogicUri = fmt.Sprintf("http://%s:%d/%s", Config.Global.Neatlogic.Host, Config.Global.Neatlogic.Port, Config.Global.Neatlogic.Tenant)
+	uri := fmt.Sprintf("%s://%s", scheme, host)
+	if basePath := strings.Trim(n.BasePath, "/"); basePath != "" {
+		uri += "/" + basePath
+	}
+	return uri + "/" + n.Tenant
+}
 
+// LoadConfig reads and parses the configuration file at configPath.
+// The profile named by the NEATLOGIC_PROFILE environment variable is selected if set and a file
+// is given, and NEATLOGIC_* environment variables override the file (see ApplyEnv).
+//
+// Parameters:
+//   - configPath: Path to the configuration file to use; if empty only the environment is used
+//
+// Returns:
+//   - Configs: The parsed configuration
+//   - error: An error if the file cannot be opened or parsed
+func LoadConfig(configPath string) (Configs, error) {
+	return LoadProfile(configPath, "")
 }
 
-// InitWithConfigPath initializes the configuration from a custom configuration file path.
-// It reads the specified configuration file and parses it into the global Config variable.
+// LoadProfile reads and parses the configuration file at configPath and selects a named profile.
+// Settings defined by the profile override those of the global section, and NEATLOGIC_*
+// environment variables override both (see ApplyEnv). Secret references in the auth section
+// are not resolved; see ResolveSecretsCtx.
 //
 // Parameters:
-//   - configPath: Path to the configuration file to use
-func InitWithConfigPath(configPath string) {
-	// Initialize configuration file
-	Config = Configs{}
-	configFile, err := os.Open(configPath)
-	if err != nil {
-		fmt.Printf("Error opening config file: %s", err)
-		// return nil, err
+//   - configPath: Path to the configuration file to use; if empty only the environment is used
+//   - profile: Name of the profile to select; if empty NEATLOGIC_PROFILE is used when a file
+//     is given, and if that is empty too only the global section applies
+//
+// Returns:
+//   - Configs: The parsed configuration
+//   - error: An error if the file cannot be opened or parsed, or the profile does not exist
+func LoadProfile(configPath string, profile string) (Configs, error) {
+	config := Configs{}
+	var profileNodes struct {
+		Profiles map[string]yaml.Node `yaml:"profiles"`
 	}
-	defer configFile.Close()
+	if configPath != "" {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return config, fmt.Errorf("failed to open config file: %w", err)
+		}
 
-	// Parse configuration file
-	decoder := yaml.NewDecoder(configFile)
-	err = decoder.Decode(&Config)
-	if err != nil {
-		fmt.Printf("Error parsing config file: %s", err)
-		// return nil, err
+		// Parse configuration file
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return config, fmt.Errorf("failed to parse config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &profileNodes); err != nil {
+			return config, fmt.Errorf("failed to parse config file: %w", err)
+		}
 	}
-	NeatlogicUri = fmt.Sprintf("http://%s:%d/%s", Config.Global.Neatlogic.Host, Config.Global.Neatlogic.Port, Config.Global.Neatlogic.Tenant)
 
+	// Select profile: decoding its node over Global only replaces the keys the profile defines.
+	// NEATLOGIC_PROFILE only applies to a configuration file, so that it does not break
+	// a configuration from the environment alone.
+	if profile == "" && configPath != "" {
+		profile = os.Getenv(EnvProfile)
+	}
+	if profile != "" {
+		node, ok := profileNodes.Profiles[profile]
+		if !ok {
+			return config, fmt.Errorf("profile %q not found in config file", profile)
+		}
+		if err := node.Decode(&config.Global); err != nil {
+			return config, fmt.Errorf("failed to parse profile %q: %w", profile, err)
+		}
+	}
+
+	if err := ApplyEnv(&config); err != nil {
+		return config, err
+	}
+	return config, nil
 }
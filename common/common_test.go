@@ -0,0 +1,43 @@
+package common_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hejingwen098/neatapi/common"
+)
+
+func TestLoadProfileEnvProfile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yml")
+	data := "global:\n  neatlogic:\n    tenant: demo\nprofiles:\n  prod:\n    neatlogic:\n      tenant: prod\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(common.EnvProfile, "prod")
+
+	config, err := common.LoadProfile(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Global.Neatlogic.Tenant; got != "prod" {
+		t.Errorf("tenant = %q, want prod", got)
+	}
+
+	// Without a file the environment alone configures the client
+	t.Setenv(common.EnvTenant, "env")
+	config, err = common.LoadProfile("", "")
+	if err != nil {
+		t.Fatalf("no file: %v", err)
+	}
+	if got := config.Global.Neatlogic.Tenant; got != "env" {
+		t.Errorf("no file: tenant = %q, want env", got)
+	}
+
+	if _, err := common.LoadProfile("", "prod"); err == nil {
+		t.Error("explicit profile without a file: got no error")
+	}
+	if _, err := common.LoadProfile(file, "staging"); err == nil {
+		t.Error("unknown profile: got no error")
+	}
+}
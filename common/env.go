@@ -0,0 +1,98 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Environment variables that override the configuration file.
+const (
+	// EnvProfile selects the profile of the configuration file; it is ignored without a file.
+	EnvProfile = "NEATLOGIC_PROFILE"
+	// EnvHost overrides global.neatlogic.host.
+	EnvHost = "NEATLOGIC_HOST"
+	// EnvPort overrides global.neatlogic.port.
+	EnvPort = "NEATLOGIC_PORT"
+	// EnvTenant overrides global.neatlogic.tenant.
+	EnvTenant = "NEATLOGIC_TENANT"
+	// EnvScheme overrides global.neatlogic.scheme.
+	EnvScheme = "NEATLOGIC_SCHEME"
+	// EnvBasePath overrides global.neatlogic.base_path.
+	EnvBasePath = "NEATLOGIC_BASE_PATH"
+	// EnvCAFile overrides global.neatlogic.tls.ca_file.
+	EnvCAFile = "NEATLOGIC_CA_FILE"
+	// EnvCertFile overrides global.neatlogic.tls.cert_file.
+	EnvCertFile = "NEATLOGIC_CERT_FILE"
+	// EnvKeyFile overrides global.neatlogic.tls.key_file.
+	EnvKeyFile = "NEATLOGIC_KEY_FILE"
+	// EnvInsecureSkipVerify overrides global.neatlogic.tls.insecure_skip_verify.
+	EnvInsecureSkipVerify = "NEATLOGIC_INSECURE_SKIP_VERIFY"
+	// EnvAuthType overrides global.auth.type.
+	EnvAuthType = "NEATLOGIC_AUTH_TYPE"
+	// EnvUsername overrides global.auth.username.
+	EnvUsername = "NEATLOGIC_USERNAME"
+	// EnvPassword overrides global.auth.password.
+	EnvPassword = "NEATLOGIC_PASSWORD"
+	// EnvEncrypt overrides global.auth.encrypt.
+	EnvEncrypt = "NEATLOGIC_ENCRYPT"
+	// EnvToken overrides global.auth.token.
+	EnvToken = "NEATLOGIC_TOKEN"
+	// EnvAccessKey overrides global.auth.access_key.
+	EnvAccessKey = "NEATLOGIC_ACCESS_KEY"
+	// EnvSecretKey overrides global.auth.secret_key.
+	EnvSecretKey = "NEATLOGIC_SECRET_KEY"
+	// EnvTokenCache overrides global.auth.token_cache.
+	EnvTokenCache = "NEATLOGIC_TOKEN_CACHE"
+	// EnvEncryptionKeyFile overrides global.auth.encryption_key_file.
+	EnvEncryptionKeyFile = "NEATLOGIC_ENCRYPTION_KEY_FILE"
+)
+
+// ApplyEnv overrides the effective settings in config.Global with the NEATLOGIC_*
+// environment variables that are set and not empty.
+//
+// Parameters:
+//   - config: Configuration to update in place
+//
+// Returns:
+//   - error: An error if a variable holds an invalid value
+func ApplyEnv(config *Configs) error {
+	setString(&config.Global.Neatlogic.Host, EnvHost)
+	setString(&config.Global.Neatlogic.Tenant, EnvTenant)
+	setString(&config.Global.Neatlogic.Scheme, EnvScheme)
+	setString(&config.Global.Neatlogic.BasePath, EnvBasePath)
+	setString(&config.Global.Neatlogic.TLS.CAFile, EnvCAFile)
+	setString(&config.Global.Neatlogic.TLS.CertFile, EnvCertFile)
+	setString(&config.Global.Neatlogic.TLS.KeyFile, EnvKeyFile)
+	setString(&config.Global.Auth.Type, EnvAuthType)
+	setString(&config.Global.Auth.Username, EnvUsername)
+	setString(&config.Global.Auth.Password, EnvPassword)
+	setString(&config.Global.Auth.Encrypt, EnvEncrypt)
+	setString(&config.Global.Auth.Token, EnvToken)
+	setString(&config.Global.Auth.AccessKey, EnvAccessKey)
+	setString(&config.Global.Auth.SecretKey, EnvSecretKey)
+	setString(&config.Global.Auth.TokenCache, EnvTokenCache)
+	setString(&config.Global.Auth.EncryptionKeyFile, EnvEncryptionKeyFile)
+	if value := os.Getenv(EnvPort); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %q", EnvPort, value)
+		}
+		config.Global.Neatlogic.Port = port
+	}
+	if value := os.Getenv(EnvInsecureSkipVerify); value != "" {
+		insecure, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %q", EnvInsecureSkipVerify, value)
+		}
+		config.Global.Neatlogic.TLS.InsecureSkipVerify = insecure
+	}
+	return nil
+}
+
+// setString replaces *field with the value of the environment variable key if it is set.
+func setString(field *string, key string) {
+	if value := os.Getenv(key); value != "" {
+		*field = value
+	}
+}
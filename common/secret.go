@@ -0,0 +1,273 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Prefixes of secret references in config.yml. A secret value without one of them is used as is.
+const (
+	// SecretEnv reads the secret from an environment variable: env:NAME.
+	SecretEnv = "env:"
+	// SecretFile reads the secret from a file, e.g. a mounted Kubernetes secret: file:/path.
+	// Trailing line breaks are removed.
+	SecretFile = "file:"
+	// SecretExec runs a command and uses its standard output, e.g. exec:pass show neatlogic.
+	// The command is split at white space and run without a shell. Trailing line breaks are removed.
+	SecretExec = "exec:"
+	// SecretEncrypted decrypts a value created by EncryptSecret with the encryption key file: enc:<data>.
+	SecretEncrypted = "enc:"
+	// SecretPlain marks a literal secret, so that a password starting with one of the other
+	// prefixes can be written as plain:env:....
+	//
+	// Compatibility note: before secret references were supported, such a password was used as is.
+	// It now has to be written with this prefix, e.g. a password env:x as plain:env:x.
+	SecretPlain = "plain:"
+)
+
+// SecretExecTimeout is the time an exec: command may run before it is killed.
+// A shorter deadline of the context passed to ResolveSecretCtx takes precedence.
+const SecretExecTimeout = 30 * time.Second
+
+// secretKeySize is the size of the AES-256 key used for encrypted secrets.
+const secretKeySize = 32
+
+// DefaultSecretKeyFile returns the encryption key file used when auth.encryption_key_file is not set,
+// below the user's config directory (e.g. ~/.config/neatapi/secret.key on Linux).
+func DefaultSecretKeyFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "neatapi", "secret.key"), nil
+}
+
+// ResolveSecrets replaces the secret references in auth that its authentication method needs.
+// See ResolveSecretsCtx.
+//
+// Parameters:
+//   - auth: Authentication configuration to update in place
+//
+// Returns:
+//   - error: An error if a referenced secret cannot be read or decrypted
+func ResolveSecrets(auth *Auth) error {
+	return ResolveSecretsCtx(context.Background(), auth)
+}
+
+// ResolveSecretsCtx replaces the secret references in auth that its authentication method
+// (see Auth.Method) needs: password and token for password, token for token and secret_key
+// for accesskey authentication. The other fields are left as they are, so that an unused
+// reference cannot fail. Commands of exec: references are bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of exec: commands
+//   - auth: Authentication configuration to update in place
+//
+// Returns:
+//   - error: An error if a referenced secret cannot be read or decrypted
+func ResolveSecretsCtx(ctx context.Context, auth *Auth) error {
+	type secret struct {
+		name  string
+		value *string
+	}
+	var secrets []secret
+	switch auth.Method() {
+	case "password":
+		secrets = []secret{{"auth.password", &auth.Password}, {"auth.token", &auth.Token}}
+	case "token":
+		secrets = []secret{{"auth.token", &auth.Token}}
+	case "accesskey":
+		secrets = []secret{{"auth.secret_key", &auth.SecretKey}}
+	}
+	for _, secret := range secrets {
+		resolved, err := ResolveSecretCtx(ctx, *secret.value, auth.EncryptionKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", secret.name, err)
+		}
+		*secret.value = resolved
+	}
+	return nil
+}
+
+// ResolveSecret returns the secret value refers to. See ResolveSecretCtx.
+//
+// Parameters:
+//   - value: The secret or a reference to it
+//   - keyFile: Encryption key file for enc: values; DefaultSecretKeyFile is used if empty
+//
+// Returns:
+//   - string: The secret
+//   - error: An error if the secret cannot be read or decrypted
+func ResolveSecret(value string, keyFile string) (string, error) {
+	return ResolveSecretCtx(context.Background(), value, keyFile)
+}
+
+// ResolveSecretCtx returns the secret value refers to, as described by the Secret* prefixes.
+// The command of an exec: reference is killed when ctx is done or after SecretExecTimeout.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of an exec: command
+//   - value: The secret or a reference to it
+//   - keyFile: Encryption key file for enc: values; DefaultSecretKeyFile is used if empty
+//
+// Returns:
+//   - string: The secret
+//   - error: An error if the secret cannot be read or decrypted
+func ResolveSecretCtx(ctx context.Context, value string, keyFile string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, SecretPlain):
+		return strings.TrimPrefix(value, SecretPlain), nil
+	case strings.HasPrefix(value, SecretEnv):
+		name := strings.TrimPrefix(value, SecretEnv)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, SecretFile):
+		data, err := os.ReadFile(strings.TrimPrefix(value, SecretFile))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, SecretExec):
+		args := strings.Fields(strings.TrimPrefix(value, SecretExec))
+		if len(args) == 0 {
+			return "", errors.New("no command given")
+		}
+		ctx, cancel := context.WithTimeout(ctx, SecretExecTimeout)
+		defer cancel()
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("command %s aborted: %w", args[0], ctx.Err())
+		}
+		if err != nil {
+			return "", fmt.Errorf("command %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return strings.TrimRight(string(out), "\r\n"), nil
+	case strings.HasPrefix(value, SecretEncrypted):
+		if keyFile == "" {
+			var err error
+			if keyFile, err = DefaultSecretKeyFile(); err != nil {
+				return "", err
+			}
+		}
+		key, err := LoadSecretKey(keyFile)
+		if err != nil {
+			return "", err
+		}
+		return DecryptSecret(strings.TrimPrefix(value, SecretEncrypted), key)
+	}
+	return value, nil
+}
+
+// GenerateSecretKey returns a new random AES-256 key for EncryptSecret.
+func GenerateSecretKey() ([]byte, error) {
+	key := make([]byte, secretKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// EncodeSecretKey returns key in the format of an encryption key file, base64 with a trailing line break.
+func EncodeSecretKey(key []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(key) + "\n")
+}
+
+// LoadSecretKey reads an encryption key file written with EncodeSecretKey.
+//
+// Parameters:
+//   - path: Path to the key file
+//
+// Returns:
+//   - []byte: The AES-256 key
+//   - error: An error if the file cannot be read or holds no valid key
+func LoadSecretKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != secretKeySize {
+		return nil, fmt.Errorf("invalid encryption key in %s", path)
+	}
+	return key, nil
+}
+
+// EncryptSecret encrypts plaintext with AES-256-GCM under key.
+//
+// Parameters:
+//   - plaintext: The secret to encrypt
+//   - key: The AES-256 key, e.g. from LoadSecretKey
+//
+// Returns:
+//   - string: The encrypted secret, prefixed with enc: to be used in config.yml
+//   - error: An error if the key is invalid
+func EncryptSecret(plaintext string, key []byte) (string, error) {
+	gcm, err := newSecretCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return SecretEncrypted + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret decrypts a secret encrypted with EncryptSecret, without its enc: prefix.
+//
+// Parameters:
+//   - data: The base64 encoded nonce and ciphertext
+//   - key: The AES-256 key the secret was encrypted with
+//
+// Returns:
+//   - string: The secret
+//   - error: An error if data is malformed or was encrypted with another key
+func DecryptSecret(data string, key []byte) (string, error) {
+	gcm, err := newSecretCipher(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted secret: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted secret: too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt secret: wrong key or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+// newSecretCipher creates the AES-GCM cipher for key.
+func newSecretCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != secretKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes", secretKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
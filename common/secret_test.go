@@ -0,0 +1,177 @@
+package common_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/hejingwen098/neatapi/common"
+)
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("NEATAPI_TEST_SECRET", "from-env")
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from-file\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"literal", "literal", false},
+		{"env:NEATAPI_TEST_SECRET", "from-env", false},
+		{"env:NEATAPI_TEST_UNSET", "", true},
+		{"file:" + file, "from-file", false},
+		{"file:" + file + ".missing", "", true},
+		{"plain:env:NEATAPI_TEST_SECRET", "env:NEATAPI_TEST_SECRET", false},
+		{"exec:", "", true},
+	}
+	for _, tt := range tests {
+		got, err := common.ResolveSecret(tt.value, "")
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %q, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.value, err)
+		} else if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolveSecretExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix commands")
+	}
+	got, err := common.ResolveSecret("exec:echo hunter2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "hunter2" {
+		t.Errorf("got %q, want hunter2", got)
+	}
+	if _, err := common.ResolveSecret("exec:false", ""); err == nil {
+		t.Error("failing command: got no error")
+	}
+}
+
+func TestResolveSecretExecContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix commands")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := common.ResolveSecretCtx(ctx, "exec:sleep 10", ""); err == nil {
+		t.Fatal("got no error for a command outliving ctx")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command ran for %v after ctx expired", elapsed)
+	}
+}
+
+func TestResolveSecretEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	key, err := common.GenerateSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "secret.key")
+	if err := os.WriteFile(keyFile, common.EncodeSecretKey(key), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := common.EncryptSecret("hunter2", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := common.ResolveSecret(encrypted, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "hunter2" {
+		t.Errorf("got %q, want hunter2", got)
+	}
+
+	other, _ := common.GenerateSecretKey()
+	otherFile := filepath.Join(dir, "other.key")
+	if err := os.WriteFile(otherFile, common.EncodeSecretKey(other), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := common.ResolveSecret(encrypted, otherFile); err == nil {
+		t.Error("decrypted with the wrong key")
+	}
+}
+
+func TestResolveSecretsOnlyNeeded(t *testing.T) {
+	t.Setenv("NEATAPI_TEST_SECRET", "from-env")
+	const unset = "env:NEATAPI_TEST_UNSET"
+	tests := []struct {
+		name    string
+		auth    common.Auth
+		want    common.Auth
+		wantErr bool
+	}{
+		{
+			name: "password",
+			auth: common.Auth{Username: "admin", Password: "env:NEATAPI_TEST_SECRET", SecretKey: unset},
+			want: common.Auth{Username: "admin", Password: "from-env", SecretKey: unset},
+		},
+		{
+			name: "token",
+			auth: common.Auth{Type: "token", Token: "env:NEATAPI_TEST_SECRET", Password: unset},
+			want: common.Auth{Type: "token", Token: "from-env", Password: unset},
+		},
+		{
+			name: "accesskey",
+			auth: common.Auth{Type: "AccessKey", AccessKey: "ak", SecretKey: "env:NEATAPI_TEST_SECRET", Password: unset},
+			want: common.Auth{Type: "AccessKey", AccessKey: "ak", SecretKey: "from-env", Password: unset},
+		},
+		{
+			name:    "missing secret",
+			auth:    common.Auth{Username: "admin", Password: unset},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := tt.auth
+			err := common.ResolveSecrets(&auth)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if auth != tt.want {
+				t.Errorf("got %+v, want %+v", auth, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProfileKeepsSecretReferences(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yml")
+	data := "global:\n  auth:\n    username: admin\n    password: env:NEATAPI_TEST_UNSET\n"
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := common.LoadProfile(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Global.Auth.Password; got != "env:NEATAPI_TEST_UNSET" {
+		t.Errorf("password = %q, want the unresolved reference", got)
+	}
+}
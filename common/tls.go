@@ -0,0 +1,66 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Config builds the tls.Config described by the TLS configuration section.
+// Certificates from CAFile are trusted in addition to the system roots.
+//
+// Returns:
+//   - *tls.Config: The TLS configuration to use for the NeatLogic endpoint
+//   - error: An error if a certificate or key file cannot be loaded
+func (t TLS) Config() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	// Custom CA bundle
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", t.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// Client certificate for mTLS
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, errors.New("both cert_file and key_file are required for client certificates")
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// HTTPClient builds an HTTP client that applies the TLS settings of the configuration section.
+//
+// Returns:
+//   - *http.Client: The HTTP client to use for the NeatLogic endpoint
+//   - error: An error if the TLS configuration cannot be built
+func (n Neatlogic) HTTPClient() (*http.Client, error) {
+	tlsConfig, err := n.TLS.Config()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
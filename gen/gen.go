@@ -0,0 +1,256 @@
+// Package gen generates Go source code from NeatLogic CI models.
+// For every CI it emits a struct tagged for neatlogic.Decode, name constants for the CI,
+// its attributes and relations, and a typed query wrapping neatlogic.QueryBuilder.
+// Compiling against the generated code turns a renamed attribute into a build failure
+// instead of data silently missing from AttrEntityData.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+)
+
+// initialisms are name parts written in upper case, following Go naming conventions.
+var initialisms = map[string]bool{
+	"API": true, "CPU": true, "DB": true, "DNS": true, "HTTP": true, "HTTPS": true,
+	"ID": true, "IP": true, "JSON": true, "MAC": true, "OS": true, "SN": true,
+	"SSH": true, "TCP": true, "UDP": true, "URI": true, "URL": true, "UUID": true,
+	"VIP": true, "VM": true,
+}
+
+// Generate returns the gofmt-formatted source of a Go file in package pkg
+// with the generated types and helpers for cis.
+// The CIs must have been retrieved with their attributes and relations, e.g. by GetCi.
+//
+// Parameters:
+//   - pkg: Name of the generated package
+//   - cis: CI models to generate code for
+//
+// Returns:
+//   - []byte: The generated source
+//   - error: An error if the generated source cannot be formatted
+func Generate(pkg string, cis []neatlogic.Ci) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by neatapi gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintf(&buf, "import (\n\t\"context\"\n")
+	if needsTime(cis) {
+		fmt.Fprintf(&buf, "\t\"time\"\n")
+	}
+	fmt.Fprintf(&buf, "\n\t\"github.com/hejingwen098/neatapi/neatlogic\"\n)\n")
+
+	typeNames := newNameSet()
+	for _, ci := range cis {
+		writeCi(&buf, ci, typeNames.add(goName(ci.Name)))
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return src, nil
+}
+
+// writeCi writes the struct, constants and query helpers for a single CI.
+func writeCi(buf *bytes.Buffer, ci neatlogic.Ci, typeName string) {
+	// Reserve the names of the entity fields and query methods
+	fields := newNameSet("ID", "Name", "UUID")
+	methods := newNameSet("Keyword", "PageSize", "Attr", "Rel", "Build", "QueryBuilder")
+	consts := newNameSet()
+
+	type member struct {
+		constName string
+		fieldName string
+		method    string
+		name      string
+		label     string
+		goType    string
+		tag       string
+		isRel     bool
+	}
+	var members []member
+	for _, attr := range ci.Attrs {
+		base := goName(attr.Name)
+		members = append(members, member{
+			constName: consts.add(typeName + "Attr" + base),
+			fieldName: fields.addWithSuffix(base, "Attr"),
+			method:    methods.addWithSuffix(base, "Attr"),
+			name:      attr.Name,
+			label:     attr.Label,
+			goType:    attrGoType(attr),
+			tag:       "attr=" + attr.Name,
+		})
+	}
+	for _, rel := range ci.Rels {
+		base := goName(rel.Name())
+		members = append(members, member{
+			constName: consts.add(typeName + "Rel" + base),
+			fieldName: fields.addWithSuffix(base, "Rel"),
+			method:    methods.addWithSuffix(base, "Rel"),
+			name:      rel.Name(),
+			label:     relLabel(rel),
+			goType:    "[]neatlogic.EntityRef",
+			tag:       "rel=" + rel.Name(),
+			isRel:     true,
+		})
+	}
+
+	// Name constants
+	fmt.Fprintf(buf, "\n// %sCiName is the name of CI %s.\n", typeName, describe(ci.Name, ci.Label))
+	fmt.Fprintf(buf, "const %sCiName = %q\n", typeName, ci.Name)
+	if len(members) > 0 {
+		fmt.Fprintf(buf, "\n// Attribute and relation names of CI %s.\nconst (\n", ci.Name)
+		for _, m := range members {
+			fmt.Fprintf(buf, "\t%s = %q\n", m.constName, m.name)
+		}
+		fmt.Fprintf(buf, ")\n")
+	}
+
+	// Entity struct
+	fmt.Fprintf(buf, "\n// %s is a cientity of CI %s.\n", typeName, describe(ci.Name, ci.Label))
+	fmt.Fprintf(buf, "type %s struct {\n", typeName)
+	fmt.Fprintf(buf, "\tID int64 `neat:\"id\"`\n")
+	fmt.Fprintf(buf, "\tName string `neat:\"name\"`\n")
+	fmt.Fprintf(buf, "\tUUID string `neat:\"uuid\"`\n")
+	for _, m := range members {
+		if m.label != "" {
+			fmt.Fprintf(buf, "\t// %s is %s.\n", m.fieldName, m.label)
+		}
+		fmt.Fprintf(buf, "\t%s %s `neat:%q`\n", m.fieldName, m.goType, m.tag)
+	}
+	fmt.Fprintf(buf, "}\n")
+
+	// Typed query
+	fmt.Fprintf(buf, "\n// %sQuery is a typed query over cientities of CI %s.\n", typeName, ci.Name)
+	fmt.Fprintf(buf, "type %sQuery struct {\n\t*neatlogic.QueryBuilder\n}\n", typeName)
+	fmt.Fprintf(buf, "\n// New%sQuery starts a typed query. ci must be CI %s, e.g. from Get%sCi.\n", typeName, ci.Name, typeName)
+	fmt.Fprintf(buf, "func New%sQuery(ci neatlogic.Ci) %sQuery {\n\treturn %sQuery{neatlogic.Query(ci)}\n}\n", typeName, typeName, typeName)
+	fmt.Fprintf(buf, "\n// Keyword restricts the search to cientities matching keyword.\n")
+	fmt.Fprintf(buf, "func (q %sQuery) Keyword(keyword string) %sQuery {\n\tq.QueryBuilder.Keyword(keyword)\n\treturn q\n}\n", typeName, typeName)
+	fmt.Fprintf(buf, "\n// PageSize sets the number of cientities per page requested from NeatLogic.\n")
+	fmt.Fprintf(buf, "func (q %sQuery) PageSize(pageSize int) %sQuery {\n\tq.QueryBuilder.PageSize(pageSize)\n\treturn q\n}\n", typeName, typeName)
+	for _, m := range members {
+		kind, builder := "attribute", "Attr"
+		if m.isRel {
+			kind, builder = "relation", "Rel"
+		}
+		fmt.Fprintf(buf, "\n// %s starts a filter on %s %s.\n", m.method, kind, m.name)
+		fmt.Fprintf(buf, "func (q %sQuery) %s() neatlogic.TypedCondition[%sQuery] {\n", typeName, m.method, typeName)
+		fmt.Fprintf(buf, "\treturn neatlogic.NewTypedCondition(q.%s(%s), q)\n}\n", builder, m.constName)
+	}
+
+	// Client helpers
+	fmt.Fprintf(buf, "\n// Get%sCi retrieves the model of CI %s.\n", typeName, ci.Name)
+	fmt.Fprintf(buf, "func Get%sCi(ctx context.Context, c *neatlogic.NeatClient) (neatlogic.Ci, error) {\n", typeName)
+	fmt.Fprintf(buf, "\treturn c.GetCiByNameCtx(ctx, %sCiName)\n}\n", typeName)
+	fmt.Fprintf(buf, "\n// Search%s runs q and decodes the matching cientities.\n", typeName)
+	fmt.Fprintf(buf, "func Search%s(ctx context.Context, c *neatlogic.NeatClient, q %sQuery) ([]%s, error) {\n", typeName, typeName, typeName)
+	fmt.Fprintf(buf, "\treqbody, err := q.Build()\n\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	fmt.Fprintf(buf, "\tentities, err := c.SearchCientityByFilterCtx(ctx, reqbody)\n\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	fmt.Fprintf(buf, "\tvar out []%s\n\tif err := neatlogic.DecodeAll(entities, &out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn out, nil\n}\n", typeName)
+}
+
+// attrGoType returns the Go type of the struct field for attr.
+func attrGoType(attr neatlogic.CiAttr) string {
+	if attr.TargetCiId != 0 {
+		return "[]neatlogic.EntityRef"
+	}
+	switch strings.ToLower(attr.Type) {
+	case "date", "datetime", "time":
+		return "time.Time"
+	case "number", "float", "double":
+		return "float64"
+	case "int", "integer":
+		return "int64"
+	case "boolean", "bool":
+		return "bool"
+	}
+	return "string"
+}
+
+// needsTime reports whether any generated struct has a time.Time field.
+func needsTime(cis []neatlogic.Ci) bool {
+	for _, ci := range cis {
+		for _, attr := range ci.Attrs {
+			if attrGoType(attr) == "time.Time" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// relLabel returns the label of rel as seen from the CI it was listed for.
+func relLabel(rel neatlogic.CiRel) string {
+	if rel.Direction == neatlogic.RelTo {
+		return rel.FromLabel
+	}
+	return rel.ToLabel
+}
+
+// describe formats a name with its label for doc comments.
+func describe(name, label string) string {
+	if label == "" || label == name {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, label)
+}
+
+// goName converts a NeatLogic name such as "os_version" or "ip-addr" to an exported Go identifier.
+func goName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, part := range parts {
+		if upper := strings.ToUpper(part); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	ident := b.String()
+	// Names starting with a digit or a letter without case (e.g. 主机名) would not be exported,
+	// and Decode skips unexported fields
+	if ident == "" || !unicode.IsUpper([]rune(ident)[0]) {
+		ident = "X" + ident
+	}
+	return ident
+}
+
+// nameSet hands out unique identifiers.
+type nameSet map[string]bool
+
+// newNameSet creates a name set with reserved names.
+func newNameSet(reserved ...string) nameSet {
+	set := nameSet{}
+	for _, name := range reserved {
+		set[name] = true
+	}
+	return set
+}
+
+// add reserves name, appending a number if it is taken.
+func (s nameSet) add(name string) string {
+	unique := name
+	for i := 2; s[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	s[unique] = true
+	return unique
+}
+
+// addWithSuffix reserves name, appending suffix (and then a number) if it is taken.
+func (s nameSet) addWithSuffix(name, suffix string) string {
+	if s[name] {
+		name += suffix
+	}
+	return s.add(name)
+}
@@ -0,0 +1,80 @@
+package gen
+
+import (
+	"bytes"
+	"flag"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+)
+
+func TestGoName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"os_version", "OSVersion"},
+		{"ip-addr", "IPAddr"},
+		{"uuid", "UUID"},
+		{"2nd_disk", "X2ndDisk"},
+		{"主机名", "X主机名"},
+		{"主机_ip", "X主机IP"},
+		{"", "X"},
+	}
+	for _, tt := range tests {
+		got := goName(tt.name)
+		if got != tt.want {
+			t.Errorf("goName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		if !token.IsExported(got) || !token.IsIdentifier(got) {
+			t.Errorf("goName(%q) = %q, not an exported identifier", tt.name, got)
+		}
+	}
+}
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// testCis are CI models covering attribute types, relations and names needing conversion.
+var testCis = []neatlogic.Ci{
+	{
+		ID:    1,
+		Name:  "host",
+		Label: "主机",
+		Attrs: []neatlogic.CiAttr{
+			{ID: 11, Name: "ip_addr", Label: "IP地址", Type: "text"},
+			{ID: 12, Name: "cpu_count", Type: "int"},
+			{ID: 13, Name: "online_date", Label: "上线日期", Type: "date"},
+			{ID: 14, Name: "主机名", Type: "text"},
+			{ID: 15, Name: "owner", Label: "负责人", TargetCiId: 2},
+			{ID: 16, Name: "name", Type: "text"},
+		},
+		Rels: []neatlogic.CiRel{
+			{ID: 21, FromName: "host", ToName: "app", ToLabel: "应用", Direction: neatlogic.RelFrom},
+			{ID: 22, FromName: "rack", FromLabel: "机柜", ToName: "host", Direction: neatlogic.RelTo},
+		},
+	},
+	{ID: 2, Name: "user"},
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate("cmdb", testCis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "cmdb.golden")
+	if *update {
+		if err := os.WriteFile(golden, src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("generated code differs from %s (run go test -update to accept):\n%s", golden, src)
+	}
+}
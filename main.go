@@ -1,10 +1,16 @@
-// Package neatapi provides a command-line entry point for the NeatLogic API SDK.
-// This package demonstrates how to use the NeatLogic SDK with example operations.
-package neatapi
+// Command neatapi is a command-line entry point for the NeatLogic API SDK.
+//
+// Without a subcommand it demonstrates how to use the NeatLogic SDK with an example search.
+// Subcommands:
+//
+//	neatapi gen     Generate Go structs and typed queries from CI models
+//	neatapi config  Create an encryption key and encrypt secrets for config.yml
+package main
 
 import (
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -12,19 +18,60 @@ import (
 )
 
 // main is the entry point for the application.
-// It demonstrates various operations using the NeatLogic SDK.
+// It dispatches to a subcommand or runs the example operations.
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "gen":
+			os.Exit(runGen(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		}
+	}
+
 	// Initialize neatClient
-	execPath, _ := os.Executable()
-	execDir := filepath.Dir(execPath)
-	configPath := flag.String("config", filepath.Join(execDir, "config.yml"), "Config file path")
+	newClient := clientFlags(flag.CommandLine)
+	ciName := flag.String("ci", "server", "Name of the CI to search")
+	keyword := flag.String("keyword", "keyword", "Keyword to search for")
+	flag.Parse()
 
-	neatClient := neatlogic.NewNeatClientWithConfigPath(*configPath)
-	cientity, err := neatClient.SearchCientityByKeyword(1491357231226880, "keyword")
+	neatClient, err := newClient()
+	if err != nil {
+		fmt.Printf("Error creating client: %v\n", err)
+		os.Exit(1)
+	}
+	ci, err := neatClient.GetCiByName(*ciName)
+	if err != nil {
+		fmt.Printf("Error getting CI: %v\n", err)
+		os.Exit(1)
+	}
+	cientity, err := neatClient.SearchCientityByKeyword(ci.ID, *keyword)
 	if err != nil {
 		fmt.Printf("Error searching entities: %v\n", err)
 	} else {
-		fmt.Printf("Found %d entities matching keyword '%s'\n", len(cientity), "keyword")
+		fmt.Printf("Found %d %s entities matching keyword '%s'\n", len(cientity), ci.Label, *keyword)
 	}
 	fmt.Println("NeatLogic SDK example completed.")
 }
+
+// clientFlags registers the -config, -profile and -log-level flags on fs.
+// The returned function creates a NeatClient from the parsed flags.
+func clientFlags(fs *flag.FlagSet) func() (*neatlogic.NeatClient, error) {
+	execPath, _ := os.Executable()
+	execDir := filepath.Dir(execPath)
+	configPath := fs.String("config", filepath.Join(execDir, "config.yml"), "Config file path")
+	profile := fs.String("profile", "", "Config profile name (defaults to $NEATLOGIC_PROFILE)")
+	logLevel := fs.String("log-level", "", "Log API requests to standard error at this level (debug, info, warn); debug includes bodies")
+	return func() (*neatlogic.NeatClient, error) {
+		opts := []neatlogic.Option{neatlogic.WithConfigPath(*configPath), neatlogic.WithProfile(*profile)}
+		if *logLevel != "" {
+			var level slog.Level
+			if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+				return nil, fmt.Errorf("invalid -log-level: %w", err)
+			}
+			logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+			opts = append(opts, neatlogic.WithLogger(logger))
+		}
+		return neatlogic.New(opts...)
+	}
+}
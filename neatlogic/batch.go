@@ -0,0 +1,256 @@
+package neatlogic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// BatchPolicy controls how BatchSaveCientity splits and parallelizes an import.
+type BatchPolicy struct {
+	// ChunkSize is the number of cientities sent in one batch save request.
+	ChunkSize int
+	// Workers is the number of batch save requests sent concurrently.
+	Workers int
+}
+
+// DefaultBatchPolicy sends chunks of 100 cientities with up to 4 concurrent requests.
+var DefaultBatchPolicy = BatchPolicy{
+	ChunkSize: 100,
+	Workers:   4,
+}
+
+// WithBatchPolicy sets the policy used by BatchSaveCientity. DefaultBatchPolicy is used by default.
+func WithBatchPolicy(policy BatchPolicy) Option {
+	return func(o *clientOptions) {
+		o.batchPolicy = policy
+	}
+}
+
+// BatchItemStatus is the outcome of a single cientity in a batch import.
+type BatchItemStatus string
+
+const (
+	// BatchCreated means the cientity was created.
+	BatchCreated BatchItemStatus = "created"
+	// BatchUpdated means the existing cientity was updated.
+	BatchUpdated BatchItemStatus = "updated"
+	// BatchFailed means the cientity could not be saved.
+	BatchFailed BatchItemStatus = "failed"
+)
+
+// BatchItemResult represents the outcome of a single cientity in a batch import.
+type BatchItemResult struct {
+	// Index is the position of the payload in the slice passed to BatchSaveCientity.
+	Index int
+	// Status is the outcome of the save.
+	Status BatchItemStatus
+	// Result contains the saved cientity ID and transaction, if the save succeeded.
+	Result SaveResult
+	// Err is the reason the save failed, if it did.
+	Err error
+}
+
+// BatchReport represents the per-item outcome of a batch import.
+type BatchReport struct {
+	// Items contains one result per payload, in the order of the payloads.
+	Items []BatchItemResult
+}
+
+// Count returns the number of items with the given status.
+func (r BatchReport) Count(status BatchItemStatus) int {
+	count := 0
+	for _, item := range r.Items {
+		if item.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed returns the items that could not be saved.
+func (r BatchReport) Failed() []BatchItemResult {
+	var failed []BatchItemResult
+	for _, item := range r.Items {
+		if item.Status == BatchFailed {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
+
+// batchSaveBody is the request body of the cientity batch save API.
+type batchSaveBody struct {
+	CiEntityList []saveCientityBody `json:"ciEntityList"`
+}
+
+// BatchSaveReturn represents the return data of the cientity batch save API.
+type BatchSaveReturn struct {
+	// TransactionGroupId is the ID of the transaction group recording the batch.
+	TransactionGroupId int64 `json:"transactionGroupId"`
+	// CiEntityList contains the saved cientities, in request order.
+	CiEntityList []SaveResult `json:"ciEntityList"`
+}
+
+// BatchSaveResponse represents the response structure for the cientity batch save API.
+type BatchSaveResponse struct {
+	// Status indicates the operation status (OK or ERROR).
+	Status string `json:"Status"`
+	// BatchSaveReturn contains the result of the batch save.
+	BatchSaveReturn BatchSaveReturn `json:"Return"`
+	// TimeCost is the time cost of the operation in milliseconds.
+	TimeCost int64 `json:"TimeCost"`
+}
+
+// BatchSaveCientity creates or updates many cientities of a CI.
+// See BatchSaveCientityCtx.
+//
+// Parameters:
+//   - ciId: The configuration item ID of all payloads
+//   - payloads: The cientities to save; a zero ID creates a new cientity
+//
+// Returns:
+//   - BatchReport: The outcome of every payload
+//   - error: An error only if the import was aborted
+func (c *NeatClient) BatchSaveCientity(ciId int64, payloads []CientityPayload) (BatchReport, error) {
+	return c.BatchSaveCientityCtx(context.Background(), ciId, payloads)
+}
+
+// BatchSaveCientityCtx creates or updates many cientities of a CI.
+// The payloads are split into chunks sent as batch save requests with bounded concurrency
+// (see WithBatchPolicy). When NeatLogic rejects a chunk with an ERROR response, its cientities
+// are saved one by one so that a bad row only fails itself; so are the cientities of a chunk
+// with a payload that cannot be sent, such as one with an invalid relation direction.
+// Any other failure of a chunk, which may have been partly committed, fails all of its
+// cientities without sending them again.
+// Every request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the import
+//   - ciId: The configuration item ID of all payloads
+//   - payloads: The cientities to save; a zero ID creates a new cientity
+//
+// Returns:
+//   - BatchReport: The outcome of every payload, including those not attempted when ctx is done
+//   - error: An error only if ctx is done before the import completed
+func (c *NeatClient) BatchSaveCientityCtx(ctx context.Context, ciId int64, payloads []CientityPayload) (BatchReport, error) {
+	policy := c.batchPolicy
+	if policy.ChunkSize <= 0 {
+		policy.ChunkSize = DefaultBatchPolicy.ChunkSize
+	}
+	if policy.Workers <= 0 {
+		policy.Workers = 1
+	}
+
+	report := BatchReport{Items: make([]BatchItemResult, len(payloads))}
+	payloads = slices.Clone(payloads)
+	for i := range payloads {
+		payloads[i].CiId = ciId
+		report.Items[i].Index = i
+	}
+
+	var wg sync.WaitGroup
+	chunks := make(chan int)
+	for i := 0; i < policy.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range chunks {
+				end := min(start+policy.ChunkSize, len(payloads))
+				c.saveChunk(ctx, payloads[start:end], report.Items[start:end])
+			}
+		}()
+	}
+
+feed:
+	for start := 0; start < len(payloads); start += policy.ChunkSize {
+		select {
+		case chunks <- start:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		// Chunks that were never sent are reported as failed too
+		for i := range report.Items {
+			if report.Items[i].Status == "" {
+				report.Items[i].Status = BatchFailed
+				report.Items[i].Err = err
+			}
+		}
+		return report, err
+	}
+	return report, nil
+}
+
+// saveChunk saves one chunk of payloads and records the outcome in results.
+func (c *NeatClient) saveChunk(ctx context.Context, payloads []CientityPayload, results []BatchItemResult) {
+	reqbody := batchSaveBody{CiEntityList: make([]saveCientityBody, len(payloads))}
+	for i, payload := range payloads {
+		body, err := payload.saveBody(editModeGlobal)
+		if err != nil {
+			// The payload cannot be sent: save one by one so that it only fails itself
+			c.saveEach(ctx, payloads, results)
+			return
+		}
+		reqbody.CiEntityList[i] = body
+	}
+
+	var respBody BatchSaveResponse
+	err := c.postJSON(ctx, "/api/rest/cmdb/cientity/batchsave", reqbody, &respBody)
+	if err == nil {
+		saved := respBody.BatchSaveReturn
+		for i, payload := range payloads {
+			result := SaveResult{
+				CiEntityId:         payload.ID,
+				TransactionGroupId: saved.TransactionGroupId,
+			}
+			if i < len(saved.CiEntityList) {
+				result = saved.CiEntityList[i]
+			}
+			results[i].Status = savedStatus(payload)
+			results[i].Result = result
+		}
+		return
+	}
+
+	// NeatLogic rejected the chunk (HTTP 200 with an ERROR envelope): find the bad rows by saving
+	// one by one. Other failures (network, 5xx, cancellation) may come after part of the chunk was
+	// committed, so the chunk is not sent again.
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusOK || apiErr.Status != "ERROR" {
+		for i := range payloads {
+			results[i].Status = BatchFailed
+			results[i].Err = err
+		}
+		return
+	}
+	c.saveEach(ctx, payloads, results)
+}
+
+// saveEach saves payloads one by one and records the outcome in results.
+func (c *NeatClient) saveEach(ctx context.Context, payloads []CientityPayload, results []BatchItemResult) {
+	for i, payload := range payloads {
+		result, err := c.SaveCientityCtx(ctx, payload)
+		if err != nil {
+			results[i].Status = BatchFailed
+			results[i].Err = err
+			continue
+		}
+		results[i].Status = savedStatus(payload)
+		results[i].Result = result
+	}
+}
+
+// savedStatus returns the status of a successfully saved payload.
+func savedStatus(payload CientityPayload) BatchItemStatus {
+	if payload.ID == 0 {
+		return BatchCreated
+	}
+	return BatchUpdated
+}
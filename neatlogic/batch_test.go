@@ -0,0 +1,96 @@
+package neatlogic
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newBatchServer starts a server answering batch saves with batchStatus and batchBody,
+// and single saves successfully. It counts the requests per endpoint.
+func newBatchServer(t *testing.T, batchStatus int, batchBody string) (*NeatClient, map[string]int) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		if strings.HasSuffix(r.URL.Path, "/batchsave") {
+			w.WriteHeader(batchStatus)
+			w.Write([]byte(batchBody))
+			return
+		}
+		json.NewEncoder(w).Encode(SaveResponse{Status: "OK", SaveReturn: SaveResult{CiEntityId: 7}})
+	}))
+	t.Cleanup(srv.Close)
+	client := &NeatClient{
+		Client:       srv.Client(),
+		NeatlogicUri: srv.URL + "/demo",
+		JwtToken:     "token",
+		batchPolicy:  DefaultBatchPolicy,
+	}
+	return client, requests
+}
+
+func TestBatchSaveCientityFallsBackOnRejectedChunk(t *testing.T) {
+	client, requests := newBatchServer(t, http.StatusOK, `{"Status":"ERROR","Message":"invalid value"}`)
+	payloads := []CientityPayload{{CiId: 1}, {CiId: 1}, {CiId: 1}}
+	report, err := client.BatchSaveCientity(1, payloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := requests["/demo/api/rest/cmdb/cientity/save"]; got != len(payloads) {
+		t.Errorf("single saves = %d, want %d", got, len(payloads))
+	}
+	for i, item := range report.Items {
+		if item.Status != BatchCreated {
+			t.Errorf("item %d: status = %s, want %s", i, item.Status, BatchCreated)
+		}
+	}
+}
+
+func TestBatchSaveCientityDoesNotResendFailedChunk(t *testing.T) {
+	for _, statusCode := range []int{http.StatusInternalServerError, http.StatusGatewayTimeout, http.StatusNotFound, http.StatusUnauthorized} {
+		client, requests := newBatchServer(t, statusCode, `{"Status":"ERROR","Message":"failed"}`)
+		report, err := client.BatchSaveCientity(1, []CientityPayload{{CiId: 1}, {CiId: 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := requests["/demo/api/rest/cmdb/cientity/save"]; got != 0 {
+			t.Errorf("status %d: single saves = %d, want 0", statusCode, got)
+		}
+		for i, item := range report.Items {
+			if item.Status != BatchFailed || item.Err == nil {
+				t.Errorf("status %d: item %d = %s (%v), want failed", statusCode, i, item.Status, item.Err)
+			}
+		}
+	}
+}
+
+func TestBatchSaveCientityFailsInvalidDirectionOnly(t *testing.T) {
+	client, requests := newBatchServer(t, http.StatusOK, `{"Status":"OK","Return":{"ciEntityList":[]}}`)
+	payloads := []CientityPayload{
+		{CiId: 1},
+		{CiId: 1, Rels: []RelValue{{RelId: 5, CiId: 2, CiEntityId: 20}}},
+		{CiId: 1},
+	}
+	report, err := client.BatchSaveCientity(1, payloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := requests["/demo/api/rest/cmdb/cientity/batchsave"]; got != 0 {
+		t.Errorf("batch saves = %d, want 0", got)
+	}
+	if got := requests["/demo/api/rest/cmdb/cientity/save"]; got != 2 {
+		t.Errorf("single saves = %d, want 2", got)
+	}
+	for i, want := range []BatchItemStatus{BatchCreated, BatchFailed, BatchCreated} {
+		if item := report.Items[i]; item.Status != want {
+			t.Errorf("item %d: status = %s (%v), want %s", i, item.Status, item.Err, want)
+		}
+	}
+}
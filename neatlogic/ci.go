@@ -0,0 +1,294 @@
+package neatlogic
+
+import (
+	"context"
+	"fmt"
+)
+
+// Ci represents a configuration item (CI) model, i.e. the schema of its cientities.
+type Ci struct {
+	// ID is the identifier of the configuration item.
+	ID int64 `json:"id"`
+	// Name is the unique name of the configuration item.
+	Name string `json:"name"`
+	// Label is the display name of the configuration item.
+	Label string `json:"label"`
+	// Description describes the configuration item.
+	Description string `json:"description"`
+	// TypeId is the identifier of the CI type (directory) the configuration item belongs to.
+	TypeId int64 `json:"typeId"`
+	// TypeName is the name of the CI type.
+	TypeName string `json:"typeName"`
+	// ParentCiId is the identifier of the parent configuration item, if it inherits one.
+	ParentCiId int64 `json:"parentCiId"`
+	// IsAbstract indicates if the configuration item is abstract and has no own cientities.
+	IsAbstract int `json:"isAbstract"`
+	// IsVirtual indicates if the configuration item is virtual.
+	IsVirtual int `json:"isVirtual"`
+	// Attrs contains the attribute definitions. It is only filled by GetCi and GetCiByName.
+	Attrs []CiAttr `json:"attrList"`
+	// Rels contains the relation definitions. It is only filled by GetCi and GetCiByName.
+	Rels []CiRel `json:"relList"`
+}
+
+// CiAttr represents an attribute definition of a configuration item.
+type CiAttr struct {
+	// ID is the identifier of the attribute.
+	ID int64 `json:"id"`
+	// CiId is the identifier of the configuration item defining the attribute.
+	CiId int64 `json:"ciId"`
+	// Name is the unique name of the attribute within the configuration item.
+	Name string `json:"name"`
+	// Label is the display name of the attribute.
+	Label string `json:"label"`
+	// Type is the attribute type, e.g. text, select, date or a reference type.
+	Type string `json:"type"`
+	// TargetCiId is the configuration item referenced by select and reference attributes.
+	TargetCiId int64 `json:"targetCiId"`
+	// IsRequired indicates if the attribute is required.
+	IsRequired int `json:"isRequired"`
+	// IsUnique indicates if the attribute value must be unique.
+	IsUnique int `json:"isUnique"`
+	// Description describes the attribute.
+	Description string `json:"description"`
+}
+
+// CiRel represents a relation definition between two configuration items.
+// A relation has a name on each end: the from-CI sees it under ToName, the to-CI under FromName.
+type CiRel struct {
+	// ID is the identifier of the relation.
+	ID int64 `json:"id"`
+	// TypeId is the identifier of the relation type.
+	TypeId int64 `json:"typeId"`
+	// TypeText is the display name of the relation type.
+	TypeText string `json:"typeText"`
+	// FromCiId is the configuration item at the source of the relation.
+	FromCiId int64 `json:"fromCiId"`
+	// FromName is the name of the relation as seen from the target.
+	FromName string `json:"fromName"`
+	// FromLabel is the display name of the relation as seen from the target.
+	FromLabel string `json:"fromLabel"`
+	// ToCiId is the configuration item at the target of the relation.
+	ToCiId int64 `json:"toCiId"`
+	// ToName is the name of the relation as seen from the source.
+	ToName string `json:"toName"`
+	// ToLabel is the display name of the relation as seen from the source.
+	ToLabel string `json:"toLabel"`
+	// Direction is the end of the relation the queried configuration item is on.
+	Direction RelDirection `json:"direction"`
+}
+
+// Name returns the name of the relation as seen from the configuration item it was listed for.
+func (r CiRel) Name() string {
+	if r.Direction == RelTo {
+		return r.FromName
+	}
+	return r.ToName
+}
+
+// PeerCiId returns the configuration item at the other end of the relation.
+func (r CiRel) PeerCiId() int64 {
+	if r.Direction == RelTo {
+		return r.FromCiId
+	}
+	return r.ToCiId
+}
+
+// Attr returns the attribute definition with the given name.
+func (ci Ci) Attr(name string) (CiAttr, bool) {
+	for _, attr := range ci.Attrs {
+		if attr.Name == name {
+			return attr, true
+		}
+	}
+	return CiAttr{}, false
+}
+
+// Rel returns the relation definition with the given name as seen from the configuration item.
+func (ci Ci) Rel(name string) (CiRel, bool) {
+	for _, rel := range ci.Rels {
+		if rel.Name() == name {
+			return rel, true
+		}
+	}
+	return CiRel{}, false
+}
+
+// ciSearchRequest is the request body of the CI search API.
+type ciSearchRequest struct {
+	Keyword     string `json:"keyword,omitempty"`
+	PageSize    int    `json:"pageSize"`
+	CurrentPage int    `json:"currentPage"`
+}
+
+// CiListReturn represents the return data of the CI search API.
+type CiListReturn struct {
+	// PageCount is the total number of pages in the result set.
+	PageCount int `json:"pageCount"`
+	// TbodyList contains the configuration items in the current page.
+	TbodyList []Ci `json:"tbodyList"`
+}
+
+// CiListResponse represents the response structure for the CI search API.
+type CiListResponse struct {
+	// Status indicates the operation status (OK or ERROR).
+	Status string `json:"Status"`
+	// CiListReturn contains the configuration items found.
+	CiListReturn CiListReturn `json:"Return"`
+	// TimeCost is the time cost of the operation in milliseconds.
+	TimeCost int64 `json:"TimeCost"`
+}
+
+// CiResponse represents the response structure for getting a configuration item.
+type CiResponse struct {
+	// Status indicates the operation status (OK or ERROR).
+	Status string `json:"Status"`
+	// CiReturn contains the configuration item.
+	CiReturn Ci `json:"Return"`
+	// TimeCost is the time cost of the operation in milliseconds.
+	TimeCost int64 `json:"TimeCost"`
+}
+
+// CiAttrListResponse represents the response structure for listing the attributes of a configuration item.
+type CiAttrListResponse struct {
+	// Status indicates the operation status (OK or ERROR).
+	Status string `json:"Status"`
+	// CiAttrListReturn contains the attribute definitions.
+	CiAttrListReturn []CiAttr `json:"Return"`
+	// TimeCost is the time cost of the operation in milliseconds.
+	TimeCost int64 `json:"TimeCost"`
+}
+
+// CiRelListResponse represents the response structure for listing the relations of a configuration item.
+type CiRelListResponse struct {
+	// Status indicates the operation status (OK or ERROR).
+	Status string `json:"Status"`
+	// CiRelListReturn contains the relation definitions.
+	CiRelListReturn []CiRel `json:"Return"`
+	// TimeCost is the time cost of the operation in milliseconds.
+	TimeCost int64 `json:"TimeCost"`
+}
+
+// ciIdRequest is the request body of APIs that take a configuration item ID.
+type ciIdRequest struct {
+	ID   int64 `json:"id,omitempty"`
+	CiId int64 `json:"ciId,omitempty"`
+}
+
+// ListCis retrieves all configuration items. Attributes and relations are not filled in.
+//
+// Returns:
+//   - []Ci: A slice of all configuration items
+//   - error: An error if the operation fails
+func (c *NeatClient) ListCis() ([]Ci, error) {
+	return c.ListCisCtx(context.Background())
+}
+
+// ListCisCtx retrieves all configuration items. Attributes and relations are not filled in.
+// Every page request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the requests
+//
+// Returns:
+//   - []Ci: A slice of all configuration items
+//   - error: An error if the operation fails
+func (c *NeatClient) ListCisCtx(ctx context.Context) ([]Ci, error) {
+	var allCi []Ci
+	for currentPage := 1; ; currentPage++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		reqbody := ciSearchRequest{
+			PageSize:    100,
+			CurrentPage: currentPage,
+		}
+		var respBody CiListResponse
+		if err := c.postJSON(withPage(ctx, currentPage), "/api/rest/cmdb/ci/search", reqbody, &respBody); err != nil {
+			return nil, err
+		}
+		allCi = append(allCi, respBody.CiListReturn.TbodyList...)
+		if currentPage >= respBody.CiListReturn.PageCount {
+			break
+		}
+	}
+	return allCi, nil
+}
+
+// GetCi retrieves a configuration item with its attribute and relation definitions.
+//
+// Parameters:
+//   - ciId: The configuration item ID
+//
+// Returns:
+//   - Ci: The configuration item
+//   - error: An error if the operation fails
+func (c *NeatClient) GetCi(ciId int64) (Ci, error) {
+	return c.GetCiCtx(context.Background(), ciId)
+}
+
+// GetCiCtx retrieves a configuration item with its attribute and relation definitions.
+// The requests are bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the requests
+//   - ciId: The configuration item ID
+//
+// Returns:
+//   - Ci: The configuration item
+//   - error: An error if the operation fails
+func (c *NeatClient) GetCiCtx(ctx context.Context, ciId int64) (Ci, error) {
+	var ciResp CiResponse
+	if err := c.postJSON(ctx, "/api/rest/cmdb/ci/get", ciIdRequest{ID: ciId}, &ciResp); err != nil {
+		return Ci{}, err
+	}
+	ci := ciResp.CiReturn
+
+	var attrResp CiAttrListResponse
+	if err := c.postJSON(ctx, "/api/rest/cmdb/ci/listattr", ciIdRequest{CiId: ciId}, &attrResp); err != nil {
+		return Ci{}, err
+	}
+	ci.Attrs = attrResp.CiAttrListReturn
+
+	rels, err := c.ListCiRelsCtx(ctx, ciId)
+	if err != nil {
+		return Ci{}, err
+	}
+	ci.Rels = rels
+	return ci, nil
+}
+
+// GetCiByName retrieves a configuration item by its unique name, with its attribute and relation definitions.
+//
+// Parameters:
+//   - name: The configuration item name
+//
+// Returns:
+//   - Ci: The configuration item
+//   - error: An error if the operation fails or no configuration item has that name
+func (c *NeatClient) GetCiByName(name string) (Ci, error) {
+	return c.GetCiByNameCtx(context.Background(), name)
+}
+
+// GetCiByNameCtx retrieves a configuration item by its unique name, with its attribute and relation definitions.
+// The requests are bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the requests
+//   - name: The configuration item name
+//
+// Returns:
+//   - Ci: The configuration item
+//   - error: An error if the operation fails or no configuration item has that name
+func (c *NeatClient) GetCiByNameCtx(ctx context.Context, name string) (Ci, error) {
+	cis, err := c.ListCisCtx(ctx)
+	if err != nil {
+		return Ci{}, err
+	}
+	for _, ci := range cis {
+		if ci.Name == name {
+			return c.GetCiCtx(ctx, ci.ID)
+		}
+	}
+	return Ci{}, fmt.Errorf("ci %q: %w", name, ErrNotFound)
+}
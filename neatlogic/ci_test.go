@@ -0,0 +1,95 @@
+package neatlogic_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+func TestListCisPaging(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	// ListCis requests 100 configuration items per page
+	for i := 1; i <= 250; i++ {
+		srv.AddCi(neatlogic.Ci{Name: fmt.Sprintf("ci-%d", i), Attrs: []neatlogic.CiAttr{{ID: int64(i)}}})
+	}
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cis, err := client.ListCis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cis) != 250 {
+		t.Fatalf("found %d configuration items, want 250", len(cis))
+	}
+	for i, ci := range cis {
+		if want := fmt.Sprintf("ci-%d", i+1); ci.Name != want || ci.ID != int64(i+1) {
+			t.Errorf("configuration item %d = %d %s, want %d %s", i, ci.ID, ci.Name, i+1, want)
+		}
+		if ci.Attrs != nil {
+			t.Errorf("configuration item %d: attributes filled in by ListCis", i)
+		}
+	}
+	if got := srv.Requests(neatlogictest.PathCiSearch); got != 3 {
+		t.Errorf("search requests = %d, want 3 pages", got)
+	}
+}
+
+func TestGetCiByName(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	attrs := []neatlogic.CiAttr{{ID: 10, CiId: 2, Name: "ip"}}
+	rels := []neatlogic.CiRel{{ID: 5, FromCiId: 2, ToCiId: 3, ToName: "app", Direction: neatlogic.RelFrom}}
+	srv.AddCi(neatlogic.Ci{ID: 1, Name: "rack"})
+	srv.AddCi(neatlogic.Ci{ID: 2, Name: "server", Label: "Server", Attrs: attrs, Rels: rels})
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ci, err := client.GetCiByName("server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := neatlogic.Ci{ID: 2, Name: "server", Label: "Server", Attrs: attrs, Rels: rels}
+	if !reflect.DeepEqual(ci, want) {
+		t.Errorf("got  %+v\nwant %+v", ci, want)
+	}
+}
+
+func TestGetCiByNameNotFound(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	srv.AddCi(neatlogic.Ci{Name: "server"})
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetCiByName("Server"); !errors.Is(err, neatlogic.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+	if got := srv.Requests(neatlogictest.PathCiGet); got != 0 {
+		t.Errorf("get requests = %d, want 0", got)
+	}
+}
+
+func TestGetCiNotFound(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetCi(99); !errors.Is(err, neatlogic.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
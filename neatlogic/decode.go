@@ -0,0 +1,415 @@
+package neatlogic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EntityRef references a related cientity, as found in RelEntityData or in reference attributes.
+type EntityRef struct {
+	// CiId is the configuration item ID of the referenced cientity.
+	CiId int64
+	// CiEntityId is the ID of the referenced cientity.
+	CiEntityId int64
+	// Name is the name of the referenced cientity.
+	Name string
+}
+
+// timeLayouts are the formats tried when decoding date and time attributes.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"15:04:05",
+}
+
+var (
+	entityRefType = reflect.TypeOf(EntityRef{})
+	timeType      = reflect.TypeOf(time.Time{})
+)
+
+// Decode copies the attributes and relations of entity into the struct pointed to by out.
+// Fields are mapped with "neat" struct tags:
+//
+//	type Server struct {
+//		ID    int64       `neat:"id"`
+//		Name  string      `neat:"name"`
+//		IP    string      `neat:"attr=ip"`
+//		Ports []int       `neat:"attr=port"`
+//		Env   string      `neat:"attr=env"`     // select attribute: display value
+//		EnvID int64       `neat:"attr=env,id"`  // select attribute: referenced ID
+//		Apps  []EntityRef `neat:"rel=application"`
+//	}
+//
+// The tag values id, name, uuid and ciId map the entity's own fields. attr=<name> and rel=<name>
+// map an attribute or relation by its name, or by its key in AttrEntityData or RelEntityData
+// (e.g. attr_123 or relfrom_456). Slice fields receive every value; other fields the first one.
+// String fields receive the display values of select and reference attributes; add the id option
+// to receive the stored IDs instead. EntityRef fields receive related or referenced cientities.
+// Attributes and relations missing from entity leave their fields untouched.
+//
+// Parameters:
+//   - entity: The cientity to decode
+//   - out: A non-nil pointer to a struct
+//
+// Returns:
+//   - error: An error if out is not a struct pointer or a value does not fit its field
+func Decode(entity TbodyList, out any) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("neatlogic: Decode requires a non-nil pointer to a struct")
+	}
+	return decodeStruct(entity, rv.Elem())
+}
+
+// DecodeAll decodes every entity into a new element appended to the slice pointed to by out.
+//
+// Parameters:
+//   - entities: The cientities to decode
+//   - out: A non-nil pointer to a slice of structs
+//
+// Returns:
+//   - error: An error if out is not a slice pointer or an entity cannot be decoded
+func DecodeAll(entities []TbodyList, out any) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Slice || rv.Elem().Type().Elem().Kind() != reflect.Struct {
+		return errors.New("neatlogic: DecodeAll requires a non-nil pointer to a slice of structs")
+	}
+	slice := rv.Elem()
+	for _, entity := range entities {
+		elem := reflect.New(slice.Type().Elem()).Elem()
+		if err := decodeStruct(entity, elem); err != nil {
+			return fmt.Errorf("cientity %d: %w", entity.ID, err)
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+	return nil
+}
+
+// decodeStruct fills the tagged fields of the struct value sv from entity.
+func decodeStruct(entity TbodyList, sv reflect.Value) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		tag, ok := field.Tag.Lookup("neat")
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+		spec, options, _ := strings.Cut(tag, ",")
+		useID := options == "id"
+		fv := sv.Field(i)
+
+		var err error
+		switch {
+		case spec == "id":
+			err = setValues(fv, []interface{}{entity.ID})
+		case spec == "name":
+			err = setValues(fv, []interface{}{entity.Name})
+		case spec == "uuid":
+			err = setValues(fv, []interface{}{entity.UUID})
+		case spec == "ciId":
+			err = setValues(fv, []interface{}{entity.CiId})
+		case strings.HasPrefix(spec, "attr="):
+			err = decodeAttr(entity, strings.TrimPrefix(spec, "attr="), fv, useID)
+		case strings.HasPrefix(spec, "rel="):
+			err = decodeRel(entity, strings.TrimPrefix(spec, "rel="), fv)
+		default:
+			err = fmt.Errorf("invalid neat tag %q", tag)
+		}
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// lookupEntry finds the entry with the given key or name in AttrEntityData or RelEntityData.
+func lookupEntry(data map[string]interface{}, name string) (map[string]interface{}, bool) {
+	if entry, ok := data[name].(map[string]interface{}); ok {
+		return entry, true
+	}
+	for _, value := range data {
+		entry, ok := value.(map[string]interface{})
+		if ok && entry["name"] == name {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// decodeAttr fills fv with the values of the named attribute.
+func decodeAttr(entity TbodyList, name string, fv reflect.Value, useID bool) error {
+	entry, ok := lookupEntry(entity.AttrEntityData, name)
+	if !ok {
+		return nil
+	}
+	valueList, _ := entry["valueList"].([]interface{})
+	actualValueList, hasActual := entry["actualValueList"].([]interface{})
+
+	// Reference attributes: pair stored IDs with their display values
+	if elemType(fv.Type()) == entityRefType {
+		targetCiId, _ := toInt64(entry["targetCiId"])
+		refs := make([]interface{}, len(valueList))
+		for i, value := range valueList {
+			ref := EntityRef{CiId: targetCiId}
+			ref.CiEntityId, _ = toInt64(value)
+			if hasActual && i < len(actualValueList) {
+				ref.Name = toString(actualValueList[i])
+			}
+			refs[i] = ref
+		}
+		return setValues(fv, refs)
+	}
+
+	values := valueList
+	if hasActual && !useID && elemType(fv.Type()).Kind() == reflect.String {
+		values = actualValueList
+	}
+	return setValues(fv, values)
+}
+
+// decodeRel fills fv with the cientities related through the named relation.
+func decodeRel(entity TbodyList, name string, fv reflect.Value) error {
+	entry, ok := lookupEntry(entity.RelEntityData, name)
+	if !ok {
+		return nil
+	}
+	valueList, _ := entry["valueList"].([]interface{})
+	values := make([]interface{}, 0, len(valueList))
+	for _, value := range valueList {
+		item, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ref := EntityRef{Name: toString(item["ciEntityName"])}
+		ref.CiId, _ = toInt64(item["ciId"])
+		ref.CiEntityId, _ = toInt64(item["ciEntityId"])
+		if elemType(fv.Type()) == entityRefType {
+			values = append(values, ref)
+		} else {
+			// Plain fields receive the names of the related cientities
+			values = append(values, ref.Name)
+		}
+	}
+	return setValues(fv, values)
+}
+
+// elemType returns the type a single value is stored as: the element type of slices,
+// with pointers dereferenced.
+func elemType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// setValues stores values into fv: all of them into a slice, the first one into any other field.
+func setValues(fv reflect.Value, values []interface{}) error {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return setValue(fv, values[0])
+}
+
+// setValue converts a single decoded JSON value and stores it into fv.
+func setValue(fv reflect.Value, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	if fv.Kind() == reflect.Pointer {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+
+	switch {
+	case fv.Type() == entityRefType:
+		ref, ok := value.(EntityRef)
+		if !ok {
+			return fmt.Errorf("cannot decode %T into EntityRef", value)
+		}
+		fv.Set(reflect.ValueOf(ref))
+		return nil
+	case fv.Type() == timeType:
+		t, err := toTime(value)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Interface:
+		fv.Set(reflect.ValueOf(value))
+	case reflect.String:
+		fv.SetString(toString(value))
+	case reflect.Bool:
+		b, err := toBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		if fv.OverflowInt(n) {
+			return fmt.Errorf("value %d overflows %s", n, fv.Type())
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		if n < 0 || fv.OverflowUint(uint64(n)) {
+			return fmt.Errorf("value %d overflows %s", n, fv.Type())
+		}
+		fv.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat64(value)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// toString formats a decoded JSON value as a string.
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case EntityRef:
+		return v.Name
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// toInt64 converts a decoded JSON number, also decoded with UseNumber, or numeric string to int64.
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case float64:
+		return int64(v), nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("cannot decode %q as integer", v)
+		}
+		return int64(f), nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot decode %q as integer", v)
+		}
+		return n, nil
+	case EntityRef:
+		return v.CiEntityId, nil
+	}
+	return 0, fmt.Errorf("cannot decode %T as integer", value)
+}
+
+// toFloat64 converts a decoded JSON number, also decoded with UseNumber, or numeric string to float64.
+func toFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("cannot decode %q as number", v)
+		}
+		return f, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot decode %q as number", v)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("cannot decode %T as number", value)
+}
+
+// toBool converts a decoded JSON boolean, number or string to bool.
+func toBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case float64:
+		return v != 0, nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return false, fmt.Errorf("cannot decode %q as boolean", v)
+		}
+		return f != 0, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, fmt.Errorf("cannot decode %q as boolean", v)
+		}
+		return b, nil
+	}
+	return false, fmt.Errorf("cannot decode %T as boolean", value)
+}
+
+// toTime converts a decoded JSON date string or millisecond timestamp to time.Time.
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case float64:
+		return time.UnixMilli(int64(v)), nil
+	case json.Number:
+		ms, err := v.Int64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("cannot decode %q as time", v)
+		}
+		return time.UnixMilli(ms), nil
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot decode %q as time", v)
+	}
+	return time.Time{}, fmt.Errorf("cannot decode %T as time", value)
+}
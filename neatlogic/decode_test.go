@@ -0,0 +1,224 @@
+package neatlogic_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+)
+
+// decodeHost is a cientity with select, reference, numeric and date attributes and a relation.
+var decodeHost = neatlogic.TbodyList{
+	ID:   42,
+	CiId: 1,
+	UUID: "b5e4",
+	Name: "web-01",
+	AttrEntityData: map[string]interface{}{
+		"attr_1": map[string]interface{}{"name": "env", "valueList": []interface{}{float64(3)}, "actualValueList": []interface{}{"prod"}},
+		"attr_2": map[string]interface{}{
+			"name":            "owner",
+			"targetCiId":      float64(9),
+			"valueList":       []interface{}{float64(11), json.Number("12")},
+			"actualValueList": []interface{}{"alice", "bob"},
+		},
+		"attr_3": map[string]interface{}{"name": "port", "valueList": []interface{}{float64(80), json.Number("443"), "8080"}},
+		"attr_4": map[string]interface{}{"name": "cpu", "valueList": []interface{}{json.Number("2.5")}},
+		"attr_5": map[string]interface{}{"name": "online", "valueList": []interface{}{"2024-01-02 03:04:05"}},
+		"attr_6": map[string]interface{}{"name": "created", "valueList": []interface{}{json.Number("1700000000000")}},
+		"attr_7": map[string]interface{}{"name": "checked", "valueList": []interface{}{float64(1700000000000)}},
+		"attr_8": map[string]interface{}{"name": "enabled", "valueList": []interface{}{json.Number("1")}},
+	},
+	RelEntityData: map[string]interface{}{
+		"relfrom_5": map[string]interface{}{
+			"name": "application",
+			"valueList": []interface{}{
+				map[string]interface{}{"ciId": float64(2), "ciEntityId": float64(20), "ciEntityName": "shop"},
+				map[string]interface{}{"ciId": json.Number("2"), "ciEntityId": json.Number("21"), "ciEntityName": "blog"},
+			},
+		},
+	},
+}
+
+func TestDecode(t *testing.T) {
+	type server struct {
+		ID       int64                 `neat:"id"`
+		CiId     int64                 `neat:"ciId"`
+		UUID     string                `neat:"uuid"`
+		Name     string                `neat:"name"`
+		Env      string                `neat:"attr=env"`
+		EnvID    int64                 `neat:"attr=env,id"`
+		EnvIDs   []string              `neat:"attr=attr_1,id"`
+		Owners   []neatlogic.EntityRef `neat:"attr=owner"`
+		Owner    neatlogic.EntityRef   `neat:"attr=owner"`
+		Ports    []int                 `neat:"attr=port"`
+		Port     uint16                `neat:"attr=port"`
+		CPU      float64               `neat:"attr=cpu"`
+		Online   time.Time             `neat:"attr=online"`
+		Created  time.Time             `neat:"attr=created"`
+		Checked  *time.Time            `neat:"attr=checked"`
+		Enabled  bool                  `neat:"attr=enabled"`
+		Apps     []neatlogic.EntityRef `neat:"rel=application"`
+		AppName  []string              `neat:"rel=relfrom_5"`
+		App      *string               `neat:"rel=application"`
+		Missing  *string               `neat:"attr=missing"`
+		Kept     string                `neat:"attr=missing"`
+		Ignored  string                `neat:"-"`
+		Untagged string
+	}
+	got := server{Kept: "kept", Ignored: "ignored", Untagged: "untagged"}
+	if err := neatlogic.Decode(decodeHost, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	app := "shop"
+	checked := time.UnixMilli(1700000000000)
+	want := server{
+		ID:       42,
+		CiId:     1,
+		UUID:     "b5e4",
+		Name:     "web-01",
+		Env:      "prod",
+		EnvID:    3,
+		EnvIDs:   []string{"3"},
+		Owners:   []neatlogic.EntityRef{{CiId: 9, CiEntityId: 11, Name: "alice"}, {CiId: 9, CiEntityId: 12, Name: "bob"}},
+		Owner:    neatlogic.EntityRef{CiId: 9, CiEntityId: 11, Name: "alice"},
+		Ports:    []int{80, 443, 8080},
+		Port:     80,
+		CPU:      2.5,
+		Online:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local),
+		Created:  time.UnixMilli(1700000000000),
+		Checked:  &checked,
+		Enabled:  true,
+		Apps:     []neatlogic.EntityRef{{CiId: 2, CiEntityId: 20, Name: "shop"}, {CiId: 2, CiEntityId: 21, Name: "blog"}},
+		AppName:  []string{"shop", "blog"},
+		App:      &app,
+		Kept:     "kept",
+		Ignored:  "ignored",
+		Untagged: "untagged",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestDecodeTime(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  time.Time
+	}{
+		{"2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2024-01-02 03:04:05", time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)},
+		{"2024-01-02 03:04", time.Date(2024, 1, 2, 3, 4, 0, 0, time.Local)},
+		{"2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)},
+		{float64(1700000000000), time.UnixMilli(1700000000000)},
+		{json.Number("1700000000000"), time.UnixMilli(1700000000000)},
+	}
+	for _, tt := range tests {
+		entity := neatlogic.TbodyList{AttrEntityData: map[string]interface{}{
+			"attr_1": map[string]interface{}{"name": "at", "valueList": []interface{}{tt.value}},
+		}}
+		var out struct {
+			At time.Time `neat:"attr=at"`
+		}
+		if err := neatlogic.Decode(entity, &out); err != nil {
+			t.Errorf("%v: %v", tt.value, err)
+			continue
+		}
+		if !out.At.Equal(tt.want) {
+			t.Errorf("%v = %v, want %v", tt.value, out.At, tt.want)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	entity := neatlogic.TbodyList{AttrEntityData: map[string]interface{}{
+		"attr_1": map[string]interface{}{"name": "text", "valueList": []interface{}{"abc"}},
+		"attr_2": map[string]interface{}{"name": "big", "valueList": []interface{}{float64(300)}},
+		"attr_3": map[string]interface{}{"name": "negative", "valueList": []interface{}{float64(-1)}},
+	}}
+	tests := []struct {
+		name string
+		out  any
+	}{
+		{"unsupported map", &struct {
+			V map[string]string `neat:"attr=text"`
+		}{}},
+		{"unsupported struct", &struct {
+			V struct{ A int } `neat:"attr=text"`
+		}{}},
+		{"unsupported channel", &struct {
+			V chan int `neat:"attr=text"`
+		}{}},
+		{"invalid tag", &struct {
+			V string `neat:"label"`
+		}{}},
+		{"not an integer", &struct {
+			V int `neat:"attr=text"`
+		}{}},
+		{"not a number", &struct {
+			V float64 `neat:"attr=text"`
+		}{}},
+		{"not a boolean", &struct {
+			V bool `neat:"attr=text"`
+		}{}},
+		{"not a time", &struct {
+			V time.Time `neat:"attr=text"`
+		}{}},
+		{"int overflow", &struct {
+			V int8 `neat:"attr=big"`
+		}{}},
+		{"uint underflow", &struct {
+			V uint `neat:"attr=negative"`
+		}{}},
+	}
+	for _, tt := range tests {
+		if err := neatlogic.Decode(entity, tt.out); err == nil {
+			t.Errorf("%s: got no error", tt.name)
+		}
+	}
+}
+
+func TestDecodeRequiresStructPointer(t *testing.T) {
+	type server struct {
+		Name string `neat:"name"`
+	}
+	var nilServer *server
+	var n int
+	for _, out := range []any{server{}, nilServer, &n, nil} {
+		if err := neatlogic.Decode(decodeHost, out); err == nil {
+			t.Errorf("Decode(%T): got no error", out)
+		}
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+	type server struct {
+		Name string `neat:"name"`
+	}
+	entities := []neatlogic.TbodyList{{Name: "web-01"}, {Name: "web-02"}}
+	got := []server{{Name: "existing"}}
+	if err := neatlogic.DecodeAll(entities, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []server{{Name: "existing"}, {Name: "web-01"}, {Name: "web-02"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	var ints []int
+	var nilSlice *[]server
+	for _, out := range []any{got, &server{}, &ints, nilSlice, nil} {
+		if err := neatlogic.DecodeAll(entities, out); err == nil {
+			t.Errorf("DecodeAll(%T): got no error", out)
+		}
+	}
+
+	var bad []struct {
+		V map[string]string `neat:"name"`
+	}
+	if err := neatlogic.DecodeAll(entities, &bad); err == nil {
+		t.Error("unsupported field type: got no error")
+	}
+}
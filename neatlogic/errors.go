@@ -0,0 +1,71 @@
+package neatlogic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors matched by APIError through errors.Is.
+var (
+	// ErrUnauthorized reports that the JWT token is missing, expired or was rejected.
+	ErrUnauthorized = errors.New("neatlogic: unauthorized")
+	// ErrForbidden reports that the user is not allowed to call the endpoint.
+	ErrForbidden = errors.New("neatlogic: forbidden")
+	// ErrNotFound reports that the endpoint or resource does not exist.
+	ErrNotFound = errors.New("neatlogic: not found")
+)
+
+// APIError represents a failed NeatLogic API call.
+// It is returned both for non-200 HTTP responses and for responses whose
+// envelope reports Status "ERROR".
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Status is the NeatLogic envelope status (OK or ERROR), if the body could be parsed.
+	Status string
+	// Message is the error message reported by NeatLogic, if any.
+	Message string
+	// Endpoint is the path of the API that was called.
+	Endpoint string
+	// TimeCost is the server-side time cost of the operation in milliseconds.
+	TimeCost int64
+	// RetryAfter is the delay requested by the Retry-After response header, if any.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	var msg string
+	if e.StatusCode != http.StatusOK {
+		msg = fmt.Sprintf("request to %s failed with status code: %d", e.Endpoint, e.StatusCode)
+	} else {
+		msg = fmt.Sprintf("request to %s returned status: %s", e.Endpoint, e.Status)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Is reports whether the error matches one of the sentinel errors,
+// based on the HTTP status code of the response.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// envelope is the common part of every NeatLogic API response.
+type envelope struct {
+	Status   string `json:"Status"`
+	Message  string `json:"Message"`
+	TimeCost int64  `json:"TimeCost"`
+}
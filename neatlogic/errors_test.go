@@ -0,0 +1,161 @@
+package neatlogic_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+)
+
+func TestParseResourceResponse(t *testing.T) {
+	sentinels := []error{neatlogic.ErrUnauthorized, neatlogic.ErrForbidden, neatlogic.ErrNotFound}
+	tests := []struct {
+		name       string
+		statusCode int
+		retryAfter string
+		body       string
+		// want is the expected error; nil means the body is returned
+		want *neatlogic.APIError
+		// is is the sentinel the error matches, if any
+		is error
+		// text is the expected error message
+		text string
+	}{
+		{
+			name:       "ok",
+			statusCode: http.StatusOK,
+			body:       `{"Status":"OK","Return":{}}`,
+		},
+		{
+			name:       "error envelope",
+			statusCode: http.StatusOK,
+			body:       `{"Status":"ERROR","Message":"invalid value","TimeCost":5}`,
+			want:       &neatlogic.APIError{StatusCode: http.StatusOK, Status: "ERROR", Message: "invalid value", TimeCost: 5},
+			text:       "request to /demo/api/rest/cmdb/cientity/save returned status: ERROR: invalid value",
+		},
+		{
+			name:       "unauthorized",
+			statusCode: http.StatusUnauthorized,
+			body:       `{"Status":"ERROR","Message":"token expired"}`,
+			want:       &neatlogic.APIError{StatusCode: http.StatusUnauthorized, Status: "ERROR", Message: "token expired"},
+			is:         neatlogic.ErrUnauthorized,
+			text:       "request to /demo/api/rest/cmdb/cientity/save failed with status code: 401: token expired",
+		},
+		{
+			name:       "forbidden",
+			statusCode: http.StatusForbidden,
+			body:       `{"Status":"ERROR","Message":"no permission"}`,
+			want:       &neatlogic.APIError{StatusCode: http.StatusForbidden, Status: "ERROR", Message: "no permission"},
+			is:         neatlogic.ErrForbidden,
+			text:       "request to /demo/api/rest/cmdb/cientity/save failed with status code: 403: no permission",
+		},
+		{
+			name:       "not found without envelope",
+			statusCode: http.StatusNotFound,
+			body:       `<html>404 Not Found</html>`,
+			want:       &neatlogic.APIError{StatusCode: http.StatusNotFound},
+			is:         neatlogic.ErrNotFound,
+			text:       "request to /demo/api/rest/cmdb/cientity/save failed with status code: 404",
+		},
+		{
+			name:       "non-200 with ok envelope",
+			statusCode: http.StatusInternalServerError,
+			body:       `{"Status":"OK"}`,
+			want:       &neatlogic.APIError{StatusCode: http.StatusInternalServerError, Status: "OK"},
+			text:       "request to /demo/api/rest/cmdb/cientity/save failed with status code: 500",
+		},
+		{
+			name:       "retry after seconds",
+			statusCode: http.StatusTooManyRequests,
+			retryAfter: "7",
+			body:       `{"Status":"ERROR","Message":"slow down"}`,
+			want:       &neatlogic.APIError{StatusCode: http.StatusTooManyRequests, Status: "ERROR", Message: "slow down", RetryAfter: 7 * time.Second},
+			text:       "request to /demo/api/rest/cmdb/cientity/save failed with status code: 429: slow down",
+		},
+		{
+			name:       "invalid retry after",
+			statusCode: http.StatusServiceUnavailable,
+			retryAfter: "soon",
+			body:       ``,
+			want:       &neatlogic.APIError{StatusCode: http.StatusServiceUnavailable},
+			text:       "request to /demo/api/rest/cmdb/cientity/save failed with status code: 503",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.statusCode,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+				Request:    httptest.NewRequest("POST", "/demo/api/rest/cmdb/cientity/save", nil),
+			}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+
+			body, err := neatlogic.ParseResourceResponse(resp)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(body) != tt.body {
+					t.Errorf("body = %s, want %s", body, tt.body)
+				}
+				return
+			}
+
+			var apiErr *neatlogic.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want an *APIError", err)
+			}
+			want := *tt.want
+			want.Endpoint = "/demo/api/rest/cmdb/cientity/save"
+			if *apiErr != want {
+				t.Errorf("got  %+v\nwant %+v", *apiErr, want)
+			}
+			if err.Error() != tt.text {
+				t.Errorf("message = %q, want %q", err.Error(), tt.text)
+			}
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.is) {
+					t.Errorf("errors.Is(err, %v) = %t", sentinel, got)
+				}
+			}
+			if body != nil {
+				t.Errorf("body = %s, want nil", body)
+			}
+		})
+	}
+}
+
+func TestParseResourceResponseRetryAfterDate(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Retry-After": {time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}},
+		Body:       io.NopCloser(strings.NewReader(`{"Status":"ERROR"}`)),
+	}
+	_, err := neatlogic.ParseResourceResponse(resp)
+	var apiErr *neatlogic.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want an *APIError", err)
+	}
+	// The date has a resolution of one second
+	if apiErr.RetryAfter <= 58*time.Second || apiErr.RetryAfter > time.Minute {
+		t.Errorf("RetryAfter = %v, want about a minute", apiErr.RetryAfter)
+	}
+}
+
+func TestAPIErrorIsWrapped(t *testing.T) {
+	err := errors.Join(errors.New("cientity 7"), &neatlogic.APIError{StatusCode: http.StatusNotFound})
+	if !errors.Is(err, neatlogic.ErrNotFound) {
+		t.Error("wrapped 404 does not match ErrNotFound")
+	}
+	if errors.Is(err, neatlogic.ErrForbidden) {
+		t.Error("wrapped 404 matches ErrForbidden")
+	}
+}
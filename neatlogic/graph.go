@@ -0,0 +1,356 @@
+package neatlogic
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TraverseOptions controls which relations Traverse follows.
+type TraverseOptions struct {
+	// MaxDepth is the number of hops from the start cientity. Zero means no limit.
+	MaxDepth int
+	// Direction restricts the walk to relations the visited cientity is on the given end of.
+	// Empty follows both directions.
+	Direction RelDirection
+	// RelNames restricts the walk to relations with these names. Empty follows all relations.
+	RelNames []string
+	// RelTypeIds restricts the walk to relations of these relation types. Empty follows all types.
+	RelTypeIds []int64
+}
+
+// GraphNode represents a cientity reached by Traverse.
+type GraphNode struct {
+	// ID is the identifier of the cientity.
+	ID int64 `json:"id"`
+	// CiId is the configuration item ID of the cientity.
+	CiId int64 `json:"ciId"`
+	// CiName is the name of the configuration item, if known.
+	CiName string `json:"ciName,omitempty"`
+	// CiLabel is the label of the configuration item, if known.
+	CiLabel string `json:"ciLabel,omitempty"`
+	// Name is the name of the cientity.
+	Name string `json:"name"`
+	// Depth is the number of hops from the start cientity.
+	Depth int `json:"depth"`
+}
+
+// GraphEdge represents a relation instance between two cientities, pointing from the
+// source end of the relation to its target end.
+type GraphEdge struct {
+	// From is the ID of the cientity at the source of the relation.
+	From int64 `json:"from"`
+	// To is the ID of the cientity at the target of the relation.
+	To int64 `json:"to"`
+	// RelId is the identifier of the relation.
+	RelId int64 `json:"relId"`
+	// Name is the name of the relation as listed on the cientity it was found on.
+	Name string `json:"name,omitempty"`
+}
+
+// Graph represents the cientities and relations reached by Traverse.
+type Graph struct {
+	// Root is the ID of the start cientity.
+	Root int64 `json:"root"`
+	// Nodes contains the reached cientities in breadth-first order, starting with the root.
+	Nodes []GraphNode `json:"nodes"`
+	// Edges contains the relations between the reached cientities, each one once.
+	Edges []GraphEdge `json:"edges"`
+}
+
+// Traverse walks the relations of a cientity breadth-first. See TraverseCtx.
+//
+// Parameters:
+//   - ciId: The configuration item ID of the start cientity
+//   - ciEntityId: The ID of the start cientity
+//   - opts: Depth limit and relation filters
+//
+// Returns:
+//   - Graph: The reached cientities and the relations between them
+//   - error: An error if the operation fails
+func (c *NeatClient) Traverse(ciId int64, ciEntityId int64, opts TraverseOptions) (Graph, error) {
+	return c.TraverseCtx(context.Background(), ciId, ciEntityId, opts)
+}
+
+// TraverseCtx walks the relations of a cientity breadth-first, fetching every cientity
+// closer than opts.MaxDepth with GetCientity and following its RelEntityData.
+// Cientities at MaxDepth are added with the details found on their neighbour but are
+// not fetched, so relations among them are not part of the graph.
+// Every cientity is visited once, so cycles in the CMDB end the walk instead of looping.
+// Every request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the requests
+//   - ciId: The configuration item ID of the start cientity
+//   - ciEntityId: The ID of the start cientity
+//   - opts: Depth limit and relation filters
+//
+// Returns:
+//   - Graph: The reached cientities and the relations between them
+//   - error: An error if the operation fails
+func (c *NeatClient) TraverseCtx(ctx context.Context, ciId int64, ciEntityId int64, opts TraverseOptions) (Graph, error) {
+	graph := Graph{
+		Root:  ciEntityId,
+		Nodes: []GraphNode{{ID: ciEntityId, CiId: ciId}},
+		Edges: []GraphEdge{},
+	}
+	nodeIndex := map[int64]int{ciEntityId: 0}
+	seenEdges := map[GraphEdge]bool{}
+	relTypes := map[int64]map[int64]int64{}
+
+	for next := 0; next < len(graph.Nodes); next++ {
+		current := graph.Nodes[next]
+		if opts.MaxDepth > 0 && current.Depth >= opts.MaxDepth {
+			continue
+		}
+		entity, err := c.GetCientityCtx(ctx, current.CiId, current.ID)
+		if err != nil {
+			return Graph{}, fmt.Errorf("cientity %d: %w", current.ID, err)
+		}
+		current.Name = entity.Name
+		current.CiName = entity.CiName
+		current.CiLabel = entity.CiLabel
+		graph.Nodes[next] = current
+
+		// Walk relations in key order so that the graph does not depend on map iteration
+		keys := make([]string, 0, len(entity.RelEntityData))
+		for key := range entity.RelEntityData {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			relId, direction, ok := parseRelKey(key)
+			if !ok || (opts.Direction != "" && direction != opts.Direction) {
+				continue
+			}
+			entry, _ := entity.RelEntityData[key].(map[string]interface{})
+			name := toString(entry["name"])
+			if len(opts.RelNames) > 0 && !slices.Contains(opts.RelNames, name) {
+				continue
+			}
+			if len(opts.RelTypeIds) > 0 {
+				typeId, err := c.relTypeId(ctx, relTypes, current.CiId, relId)
+				if err != nil {
+					return Graph{}, err
+				}
+				if !slices.Contains(opts.RelTypeIds, typeId) {
+					continue
+				}
+			}
+
+			valueList, _ := entry["valueList"].([]interface{})
+			for _, value := range valueList {
+				item, ok := value.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				peer := GraphNode{
+					CiName:  toString(item["ciName"]),
+					CiLabel: toString(item["ciLabel"]),
+					Name:    toString(item["ciEntityName"]),
+					Depth:   current.Depth + 1,
+				}
+				peer.CiId, _ = toInt64(item["ciId"])
+				peer.ID, _ = toInt64(item["ciEntityId"])
+				if peer.ID == 0 {
+					continue
+				}
+
+				edge := GraphEdge{From: current.ID, To: peer.ID, RelId: relId}
+				if direction == RelTo {
+					edge.From, edge.To = peer.ID, current.ID
+				}
+				// Both ends list the same relation instance; keep the first one found
+				if !seenEdges[edge] {
+					seenEdges[edge] = true
+					edge.Name = name
+					graph.Edges = append(graph.Edges, edge)
+				}
+
+				if _, visited := nodeIndex[peer.ID]; !visited {
+					nodeIndex[peer.ID] = len(graph.Nodes)
+					graph.Nodes = append(graph.Nodes, peer)
+				}
+			}
+		}
+	}
+	return graph, nil
+}
+
+// relTypeId returns the relation type of a relation of a CI, listing the CI's relations
+// into cache on first use.
+func (c *NeatClient) relTypeId(ctx context.Context, cache map[int64]map[int64]int64, ciId int64, relId int64) (int64, error) {
+	types, ok := cache[ciId]
+	if !ok {
+		rels, err := c.ListCiRelsCtx(ctx, ciId)
+		if err != nil {
+			return 0, fmt.Errorf("ci %d: %w", ciId, err)
+		}
+		types = make(map[int64]int64, len(rels))
+		for _, rel := range rels {
+			types[rel.ID] = rel.TypeId
+		}
+		cache[ciId] = types
+	}
+	return types[relId], nil
+}
+
+// parseRelKey splits a relEntityData key such as relfrom_456 into relation ID and direction.
+func parseRelKey(key string) (int64, RelDirection, bool) {
+	rest, ok := strings.CutPrefix(key, "rel")
+	if !ok {
+		return 0, "", false
+	}
+	direction, id, ok := strings.Cut(rest, "_")
+	if !ok || (RelDirection(direction) != RelFrom && RelDirection(direction) != RelTo) {
+		return 0, "", false
+	}
+	relId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return relId, RelDirection(direction), true
+}
+
+// WriteJSON writes the graph as an indented JSON document.
+func (g Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language.
+// Nodes are labelled with the cientity name and CI, edges with the relation name.
+func (g Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph cmdb {\n")
+	for _, node := range g.Nodes {
+		label := node.Name
+		if ci := nodeCi(node); ci != "" {
+			label += "\n" + ci
+		}
+		fmt.Fprintf(&b, "  \"%d\" [label=%s];\n", node.ID, dotQuote(label))
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  \"%d\" -> \"%d\" [label=%s];\n", edge.From, edge.To, dotQuote(edge.Name))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// nodeCi returns the label or name of the CI of node, for display.
+func nodeCi(node GraphNode) string {
+	if node.CiLabel != "" {
+		return node.CiLabel
+	}
+	return node.CiName
+}
+
+// dotQuote quotes s as a DOT string, keeping newlines as DOT line breaks.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// graphML is the root element of a GraphML document.
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+// graphMLKey declares a data attribute of GraphML nodes or edges.
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+// graphMLGraph is the graph element of a GraphML document.
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+// graphMLNode is a node element of a GraphML document.
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+// graphMLEdge is an edge element of a GraphML document.
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+// graphMLData is a data element of a GraphML node or edge.
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph as a GraphML document, with the node and edge fields as data attributes.
+func (g Graph) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "name", For: "node", AttrName: "name", AttrType: "string"},
+			{ID: "ciId", For: "node", AttrName: "ciId", AttrType: "long"},
+			{ID: "ciName", For: "node", AttrName: "ciName", AttrType: "string"},
+			{ID: "ciLabel", For: "node", AttrName: "ciLabel", AttrType: "string"},
+			{ID: "depth", For: "node", AttrName: "depth", AttrType: "int"},
+			{ID: "relId", For: "edge", AttrName: "relId", AttrType: "long"},
+			{ID: "relName", For: "edge", AttrName: "name", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "cmdb", EdgeDefault: "directed"},
+	}
+	for _, node := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: strconv.FormatInt(node.ID, 10),
+			Data: []graphMLData{
+				{Key: "name", Value: node.Name},
+				{Key: "ciId", Value: strconv.FormatInt(node.CiId, 10)},
+				{Key: "ciName", Value: node.CiName},
+				{Key: "ciLabel", Value: node.CiLabel},
+				{Key: "depth", Value: strconv.Itoa(node.Depth)},
+			},
+		})
+	}
+	for _, edge := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: strconv.FormatInt(edge.From, 10),
+			Target: strconv.FormatInt(edge.To, 10),
+			Data: []graphMLData{
+				{Key: "relId", Value: strconv.FormatInt(edge.RelId, 10)},
+				{Key: "relName", Value: edge.Name},
+			},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
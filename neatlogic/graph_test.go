@@ -0,0 +1,212 @@
+package neatlogic_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// relValue returns a relEntityData entry listing the given peers.
+func relValue(name string, peers ...map[string]interface{}) map[string]interface{} {
+	valueList := make([]interface{}, len(peers))
+	for i, peer := range peers {
+		valueList[i] = peer
+	}
+	return map[string]interface{}{"name": name, "valueList": valueList}
+}
+
+// peer returns a relation value pointing at a cientity.
+func peer(ciId int64, ciName string, id int64, name string) map[string]interface{} {
+	return map[string]interface{}{"ciId": ciId, "ciName": ciName, "ciEntityId": id, "ciEntityName": name}
+}
+
+// addTopology stores a host running two applications, one of which uses a database
+// that backs up the host, closing a cycle. Every relation is listed on both ends.
+//
+//	web-01 -runs(5)-> shop -uses(6)-> db -backup(7)-> web-01
+//	web-01 -runs(5)-> blog
+func addTopology(srv *neatlogictest.Server) {
+	web := peer(1, "host", 1, "web-01")
+	shop := peer(2, "app", 10, "shop")
+	blog := peer(2, "app", 11, "blog")
+	db := peer(3, "db", 20, "db")
+	srv.AddCientity(neatlogic.TbodyList{ID: 1, CiId: 1, CiName: "host", Name: "web-01", RelEntityData: map[string]interface{}{
+		"relfrom_5": relValue("runs", shop, blog),
+		"relto_7":   relValue("backup", db),
+	}})
+	srv.AddCientity(neatlogic.TbodyList{ID: 10, CiId: 2, CiName: "app", Name: "shop", RelEntityData: map[string]interface{}{
+		"relto_5":   relValue("runs", web),
+		"relfrom_6": relValue("uses", db),
+	}})
+	srv.AddCientity(neatlogic.TbodyList{ID: 11, CiId: 2, CiName: "app", Name: "blog", RelEntityData: map[string]interface{}{
+		"relto_5": relValue("runs", web),
+	}})
+	srv.AddCientity(neatlogic.TbodyList{ID: 20, CiId: 3, CiName: "db", Name: "db", RelEntityData: map[string]interface{}{
+		"relto_6":   relValue("uses", shop),
+		"relfrom_7": relValue("backup", web),
+	}})
+	srv.SetCiRels(1, []neatlogic.CiRel{
+		{ID: 5, TypeId: 100, FromCiId: 1, ToCiId: 2, ToName: "runs", Direction: neatlogic.RelFrom},
+		{ID: 7, TypeId: 200, FromCiId: 3, ToCiId: 1, FromName: "backup", Direction: neatlogic.RelTo},
+	})
+	srv.SetCiRels(2, []neatlogic.CiRel{
+		{ID: 5, TypeId: 100, FromCiId: 1, ToCiId: 2, FromName: "runs", Direction: neatlogic.RelTo},
+		{ID: 6, TypeId: 100, FromCiId: 2, ToCiId: 3, ToName: "uses", Direction: neatlogic.RelFrom},
+	})
+	srv.SetCiRels(3, []neatlogic.CiRel{
+		{ID: 6, TypeId: 100, FromCiId: 2, ToCiId: 3, FromName: "uses", Direction: neatlogic.RelTo},
+		{ID: 7, TypeId: 200, FromCiId: 3, ToCiId: 1, ToName: "backup", Direction: neatlogic.RelFrom},
+	})
+}
+
+func TestTraverse(t *testing.T) {
+	var (
+		web  = neatlogic.GraphNode{ID: 1, CiId: 1, CiName: "host", Name: "web-01"}
+		shop = neatlogic.GraphNode{ID: 10, CiId: 2, CiName: "app", Name: "shop", Depth: 1}
+		blog = neatlogic.GraphNode{ID: 11, CiId: 2, CiName: "app", Name: "blog", Depth: 1}
+		db   = neatlogic.GraphNode{ID: 20, CiId: 3, CiName: "db", Name: "db", Depth: 1}
+		db2  = neatlogic.GraphNode{ID: 20, CiId: 3, CiName: "db", Name: "db", Depth: 2}
+
+		runsShop = neatlogic.GraphEdge{From: 1, To: 10, RelId: 5, Name: "runs"}
+		runsBlog = neatlogic.GraphEdge{From: 1, To: 11, RelId: 5, Name: "runs"}
+		uses     = neatlogic.GraphEdge{From: 10, To: 20, RelId: 6, Name: "uses"}
+		backup   = neatlogic.GraphEdge{From: 20, To: 1, RelId: 7, Name: "backup"}
+	)
+	tests := []struct {
+		name  string
+		opts  neatlogic.TraverseOptions
+		nodes []neatlogic.GraphNode
+		edges []neatlogic.GraphEdge
+		gets  int
+	}{
+		{
+			name:  "all",
+			nodes: []neatlogic.GraphNode{web, shop, blog, db},
+			edges: []neatlogic.GraphEdge{runsShop, runsBlog, backup, uses},
+			gets:  4,
+		},
+		{
+			name:  "max depth",
+			opts:  neatlogic.TraverseOptions{MaxDepth: 1},
+			nodes: []neatlogic.GraphNode{web, shop, blog, db},
+			edges: []neatlogic.GraphEdge{runsShop, runsBlog, backup},
+			gets:  1,
+		},
+		{
+			name:  "direction",
+			opts:  neatlogic.TraverseOptions{Direction: neatlogic.RelFrom},
+			nodes: []neatlogic.GraphNode{web, shop, blog, db2},
+			edges: []neatlogic.GraphEdge{runsShop, runsBlog, uses, backup},
+			gets:  4,
+		},
+		{
+			name:  "relation names",
+			opts:  neatlogic.TraverseOptions{RelNames: []string{"runs", "uses"}},
+			nodes: []neatlogic.GraphNode{web, shop, blog, db2},
+			edges: []neatlogic.GraphEdge{runsShop, runsBlog, uses},
+			gets:  4,
+		},
+		{
+			name:  "relation types",
+			opts:  neatlogic.TraverseOptions{RelTypeIds: []int64{200}},
+			nodes: []neatlogic.GraphNode{web, db},
+			edges: []neatlogic.GraphEdge{backup},
+			gets:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := neatlogictest.NewServer()
+			defer srv.Close()
+			addTopology(srv)
+			client, err := srv.NewClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			graph, err := client.Traverse(1, 1, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if graph.Root != 1 {
+				t.Errorf("root = %d, want 1", graph.Root)
+			}
+			if !reflect.DeepEqual(graph.Nodes, tt.nodes) {
+				t.Errorf("nodes:\ngot  %+v\nwant %+v", graph.Nodes, tt.nodes)
+			}
+			if !reflect.DeepEqual(graph.Edges, tt.edges) {
+				t.Errorf("edges:\ngot  %+v\nwant %+v", graph.Edges, tt.edges)
+			}
+			// Every cientity is fetched at most once, although the relations form a cycle
+			if got := srv.Requests(neatlogictest.PathCientityGet); got != tt.gets {
+				t.Errorf("get requests = %d, want %d", got, tt.gets)
+			}
+		})
+	}
+}
+
+func TestTraverseMissingCientity(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Traverse(1, 99, neatlogic.TraverseOptions{}); err == nil {
+		t.Error("Traverse of a missing cientity succeeded")
+	}
+}
+
+// escapeGraph has names that need quoting in DOT and escaping in XML.
+var escapeGraph = neatlogic.Graph{
+	Root: 1,
+	Nodes: []neatlogic.GraphNode{
+		{ID: 1, CiId: 1, CiName: "host", CiLabel: "Host", Name: `web "01"`},
+		{ID: 2, CiId: 2, CiName: "app", Name: `C:\shop <&>`, Depth: 1},
+		{ID: 3, CiId: 3, Name: "multi\nline", Depth: 1},
+	},
+	Edges: []neatlogic.GraphEdge{
+		{From: 1, To: 2, RelId: 5, Name: `runs "as" <root>`},
+		{From: 3, To: 1, RelId: 6},
+	},
+}
+
+func TestGraphExport(t *testing.T) {
+	tests := []struct {
+		golden string
+		write  func(neatlogic.Graph, *bytes.Buffer) error
+	}{
+		{"graph.dot", func(g neatlogic.Graph, b *bytes.Buffer) error { return g.WriteDOT(b) }},
+		{"graph.graphml", func(g neatlogic.Graph, b *bytes.Buffer) error { return g.WriteGraphML(b) }},
+		{"graph.json", func(g neatlogic.Graph, b *bytes.Buffer) error { return g.WriteJSON(b) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.write(escapeGraph, &buf); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("output differs from %s (run go test -update to accept):\n%s", golden, buf.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package neatlogic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLoggedBody is the number of bytes of a request or response body logged at debug level.
+const maxLoggedBody = 4096
+
+// redacted replaces secrets in logged bodies.
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are the JSON keys, in lower case, whose values are redacted from every logged body.
+var sensitiveKeys = []string{"password", "jwttoken", "token", "authorization", "secretkey", "secret_key"}
+
+// attrValueKeys are the keys of an attrEntityData entry holding its values.
+var attrValueKeys = []string{"valueList", "actualValueList", "oldValueList", "oldActualValueList"}
+
+// contextKey is the type of the context keys of this package.
+type contextKey int
+
+const (
+	// pageKey holds the page number of a paginated request.
+	pageKey contextKey = iota
+	// attemptKey holds the attempt number of a retried request.
+	attemptKey
+	// idempotentKey marks requests that are safe to retry, see Idempotent.
+	idempotentKey
+)
+
+// WithLogger logs every request sent by the client to logger: method, endpoint, page number,
+// attempt, HTTP and NeatLogic status, TimeCost and latency. At debug level the request and
+// response bodies are logged too, with tokens, passwords and the attributes given to
+// WithSensitiveAttrs redacted. Requests are not logged by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *clientOptions) {
+		o.logger = logger
+	}
+}
+
+// WithSensitiveAttrs redacts the values of CI attributes from logged bodies. An attribute is
+// given by its name (e.g. "root_password") or its attrEntityData key (e.g. "attr_123").
+// Names are resolved to keys through the attribute list of the CI of a logged cientity,
+// fetched once per CI. If that fails, a warning is logged and all attribute values of
+// the cientities of that CI are redacted.
+func WithSensitiveAttrs(attrs ...string) Option {
+	return func(o *clientOptions) {
+		o.sensitiveAttrs = append(o.sensitiveAttrs, attrs...)
+	}
+}
+
+// withPage returns a copy of ctx recording the page number of a paginated request for logging.
+func withPage(ctx context.Context, page int) context.Context {
+	return context.WithValue(ctx, pageKey, page)
+}
+
+// withAttempt returns a copy of ctx recording the attempt number of a retried request for logging.
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey, attempt)
+}
+
+// LoggingMiddleware returns a middleware logging every request to logger, as described by WithLogger.
+// Unlike the logger of a client, it cannot resolve attribute names to attrEntityData keys, so if
+// names are given, all attribute values of logged cientities are redacted. Give attrEntityData
+// keys to redact only those attributes.
+//
+// Parameters:
+//   - logger: The logger to write to
+//   - sensitiveAttrs: Names or attrEntityData keys of CI attributes to redact from logged bodies
+//
+// Returns:
+//   - Middleware: The logging middleware
+func LoggingMiddleware(logger *slog.Logger, sensitiveAttrs ...string) Middleware {
+	return loggingMiddleware(logger, newRedactor(sensitiveAttrs, nil))
+}
+
+// loggingMiddleware returns a client's logging middleware, resolving the names of sensitive
+// attributes through the attribute lists of their CIs. A failed lookup is logged as a warning.
+func (c *NeatClient) loggingMiddleware(logger *slog.Logger, sensitiveAttrs []string) Middleware {
+	lookup := func(ctx context.Context, ciId int64) ([]CiAttr, error) {
+		attrs, err := c.lookupAttrs(ctx, ciId)
+		if err != nil {
+			logger.LogAttrs(ctx, slog.LevelWarn, "neatlogic: cannot resolve sensitive attributes, redacting all attribute values",
+				slog.Int64("ciId", ciId), slog.Any("error", err))
+		}
+		return attrs, err
+	}
+	return loggingMiddleware(logger, newRedactor(sensitiveAttrs, lookup))
+}
+
+// lookupAttrs fetches the attribute definitions of a CI for the logging middleware.
+// It sends the request with the HTTP client of c directly instead of through its middleware
+// chain, so that the lookup is neither retried, logged nor seen by other middlewares.
+func (c *NeatClient) lookupAttrs(ctx context.Context, ciId int64) ([]CiAttr, error) {
+	jsonData, err := json.Marshal(ciIdRequest{CiId: ciId})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.NeatlogicUri+"/api/rest/cmdb/ci/listattr", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := c.auth().Authenticate(ctx, req); err != nil {
+		return nil, err
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ParseResourceResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	var attrResp CiAttrListResponse
+	if err := json.Unmarshal(body, &attrResp); err != nil {
+		return nil, err
+	}
+	return attrResp.CiAttrListReturn, nil
+}
+
+// loggingMiddleware is LoggingMiddleware with the given redactor.
+func loggingMiddleware(logger *slog.Logger, r *redactor) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("endpoint", req.URL.Path),
+			}
+			if page, ok := ctx.Value(pageKey).(int); ok {
+				attrs = append(attrs, slog.Int("page", page))
+			}
+			if attempt, ok := ctx.Value(attemptKey).(int); ok {
+				attrs = append(attrs, slog.Int("attempt", attempt))
+			}
+			debug := logger.Enabled(ctx, slog.LevelDebug)
+			if debug {
+				if body, err := peekRequestBody(req); err == nil && len(body) > 0 {
+					attrs = append(attrs, slog.String("request", r.redact(ctx, body)))
+				}
+			}
+
+			start := time.Now()
+			resp, err := next.Do(req)
+			attrs = append(attrs, slog.Duration("latency", time.Since(start)))
+			if err != nil {
+				attrs = append(attrs, slog.Any("error", err))
+				logger.LogAttrs(ctx, slog.LevelWarn, "neatlogic request failed", attrs...)
+				return nil, err
+			}
+
+			// Read the body to log the envelope, and hand a copy on
+			body, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			var env envelope
+			_ = json.Unmarshal(body, &env)
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			if env.Status != "" {
+				attrs = append(attrs, slog.String("result", env.Status), slog.Int64("timeCost", env.TimeCost))
+			}
+			if debug && len(body) > 0 {
+				attrs = append(attrs, slog.String("response", r.redact(ctx, body)))
+			}
+			if readErr != nil {
+				attrs = append(attrs, slog.Any("error", readErr))
+			}
+
+			level := slog.LevelInfo
+			if resp.StatusCode != http.StatusOK || env.Status == "ERROR" || readErr != nil {
+				level = slog.LevelWarn
+			}
+			logger.LogAttrs(ctx, level, "neatlogic request", attrs...)
+			if readErr != nil {
+				return nil, readErr
+			}
+			return resp, nil
+		})
+	}
+}
+
+// peekRequestBody returns the body of req without consuming it.
+func peekRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody == nil {
+		return nil, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// attrLookup returns the attribute definitions of a CI.
+type attrLookup func(ctx context.Context, ciId int64) ([]CiAttr, error)
+
+// redactor removes secrets from JSON bodies before they are logged.
+type redactor struct {
+	// attrs holds the names and keys of sensitive CI attributes.
+	attrs map[string]bool
+	// names reports whether attrs holds names, which must be resolved to keys.
+	names bool
+	// lookup resolves names through the attribute list of a CI; nil if it is not possible.
+	lookup attrLookup
+
+	// mu guards keys.
+	mu sync.Mutex
+	// keys holds the attrEntityData keys of the sensitive attributes per CI,
+	// nil for a CI whose attribute list could not be fetched.
+	keys map[int64]map[string]bool
+}
+
+// newRedactor creates a redactor for the given sensitive CI attributes.
+// lookup may be nil.
+func newRedactor(sensitiveAttrs []string, lookup attrLookup) *redactor {
+	r := &redactor{attrs: map[string]bool{}, lookup: lookup, keys: map[int64]map[string]bool{}}
+	for _, attr := range sensitiveAttrs {
+		r.attrs[attr] = true
+		if !strings.HasPrefix(attr, "attr_") {
+			r.names = true
+		}
+	}
+	return r
+}
+
+// redact returns body with secrets replaced, truncated to maxLoggedBody bytes.
+// Bodies that are not JSON are not logged, as their secrets cannot be found.
+func (r *redactor) redact(ctx context.Context, body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Sprintf("(not JSON, %d bytes)", len(body))
+	}
+	data, err := json.Marshal(r.redactValue(ctx, value))
+	if err != nil {
+		return fmt.Sprintf("(%v)", err)
+	}
+	if len(data) > maxLoggedBody {
+		return string(data[:maxLoggedBody]) + "...(truncated)"
+	}
+	return string(data)
+}
+
+// redactValue replaces secrets in a decoded JSON value.
+func (r *redactor) redactValue(ctx context.Context, value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if name, ok := value["name"].(string); ok && r.attrs[name] {
+			r.redactAttr(value)
+		}
+		for key, field := range value {
+			switch {
+			case isSensitiveKey(key):
+				value[key] = redacted
+			case key == "attrEntityData":
+				if data, ok := field.(map[string]interface{}); ok {
+					r.redactAttrEntityData(ctx, value["ciId"], data)
+				}
+			case r.attrs[key]:
+				if entry, ok := field.(map[string]interface{}); ok {
+					r.redactAttr(entry)
+				} else {
+					value[key] = redacted
+				}
+			default:
+				value[key] = r.redactValue(ctx, field)
+			}
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = r.redactValue(ctx, item)
+		}
+		return value
+	}
+	return value
+}
+
+// redactAttrEntityData replaces the values of the sensitive attributes in the attrEntityData
+// of a cientity of the CI ciId, as found next to it in the body.
+func (r *redactor) redactAttrEntityData(ctx context.Context, ciId interface{}, data map[string]interface{}) {
+	keys, resolved := r.sensitiveKeys(ctx, ciId)
+	for key, field := range data {
+		entry, ok := field.(map[string]interface{})
+		if !ok {
+			if !resolved || r.attrs[key] || keys[key] {
+				data[key] = redacted
+			}
+			continue
+		}
+		name, _ := entry["name"].(string)
+		if !resolved || r.attrs[key] || r.attrs[name] || keys[key] {
+			r.redactAttr(entry)
+		}
+	}
+}
+
+// sensitiveKeys returns the attrEntityData keys of the sensitive attributes given by name
+// for the CI ciId. It reports false if names are given but cannot be resolved.
+func (r *redactor) sensitiveKeys(ctx context.Context, ciId interface{}) (map[string]bool, bool) {
+	if !r.names {
+		return nil, true
+	}
+	number, ok := ciId.(json.Number)
+	if !ok || r.lookup == nil {
+		return nil, false
+	}
+	id, err := number.Int64()
+	if err != nil {
+		return nil, false
+	}
+
+	r.mu.Lock()
+	keys, ok := r.keys[id]
+	r.mu.Unlock()
+	if ok {
+		return keys, keys != nil
+	}
+	// A failed lookup is not repeated, so that logging does not cause a request per body
+	attrs, err := r.lookup(ctx, id)
+	if err != nil {
+		r.mu.Lock()
+		r.keys[id] = nil
+		r.mu.Unlock()
+		return nil, false
+	}
+	keys = map[string]bool{}
+	for _, attr := range attrs {
+		if r.attrs[attr.Name] {
+			keys[fmt.Sprintf("attr_%d", attr.ID)] = true
+		}
+	}
+	r.mu.Lock()
+	r.keys[id] = keys
+	r.mu.Unlock()
+	return keys, true
+}
+
+// redactAttr replaces the values of an attrEntityData entry.
+func (r *redactor) redactAttr(entry map[string]interface{}) {
+	for _, key := range attrValueKeys {
+		if _, ok := entry[key]; ok {
+			entry[key] = redacted
+		}
+	}
+}
+
+// isSensitiveKey reports whether the value of a JSON key is always redacted.
+func isSensitiveKey(key string) bool {
+	return slices.Contains(sensitiveKeys, strings.ToLower(key))
+}
@@ -0,0 +1,162 @@
+package neatlogic_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+// secretHost is a cientity of CI 1 with the secret root_password (attr_7) hunter2.
+var secretHost = neatlogic.CientityPayload{
+	CiId: 1,
+	Attrs: []neatlogic.AttrValue{
+		{AttrId: 7, ValueList: []interface{}{"hunter2"}},
+		{AttrId: 8, ValueList: []interface{}{"web-01"}},
+	},
+}
+
+// newLoggedClient creates a client of srv logging bodies to the returned buffer.
+func newLoggedClient(t *testing.T, srv *neatlogictest.Server, opts ...neatlogic.Option) (*neatlogic.NeatClient, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client, err := srv.NewClient(append([]neatlogic.Option{neatlogic.WithLogger(logger)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, &buf
+}
+
+// saveAndGet saves secretHost with every write API and reads it back.
+func saveAndGet(t *testing.T, client *neatlogic.NeatClient) {
+	t.Helper()
+	saved, err := client.SaveCientity(secretHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UpdateCientityAttrs(1, saved.CiEntityId, secretHost.Attrs); err != nil {
+		t.Fatal(err)
+	}
+	report, err := client.BatchSaveCientity(1, []neatlogic.CientityPayload{secretHost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := report.Count(neatlogic.BatchCreated); got != 1 {
+		t.Fatalf("batch created %d cientities, want 1: %+v", got, report.Failed())
+	}
+	if _, err := client.GetCientity(1, saved.CiEntityId); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoggingRedactsAttrsByName(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	srv.SetCiAttrs(1, []neatlogic.CiAttr{{ID: 7, CiId: 1, Name: "root_password"}, {ID: 8, CiId: 1, Name: "hostname"}})
+	client, buf := newLoggedClient(t, srv, neatlogic.WithSensitiveAttrs("root_password"))
+
+	saveAndGet(t, client)
+	logged := buf.String()
+	if strings.Contains(logged, "hunter2") {
+		t.Errorf("secret logged:\n%s", logged)
+	}
+	if !strings.Contains(logged, "web-01") {
+		t.Errorf("other attribute redacted too:\n%s", logged)
+	}
+	if got := srv.Requests(neatlogictest.PathCiListAttr); got != 1 {
+		t.Errorf("attribute list requests = %d, want 1", got)
+	}
+}
+
+func TestLoggingRedactsAttrsByKey(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	client, buf := newLoggedClient(t, srv, neatlogic.WithSensitiveAttrs("attr_7"))
+
+	saveAndGet(t, client)
+	logged := buf.String()
+	if strings.Contains(logged, "hunter2") {
+		t.Errorf("secret logged:\n%s", logged)
+	}
+	if !strings.Contains(logged, "web-01") {
+		t.Errorf("other attribute redacted too:\n%s", logged)
+	}
+	if got := srv.Requests(neatlogictest.PathCiListAttr); got != 0 {
+		t.Errorf("attribute list requests = %d, want 0", got)
+	}
+}
+
+func TestLoggingRedactsAllAttrsOfUnresolvedNames(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	srv.InjectFault(neatlogictest.PathCiListAttr, neatlogictest.Fault{StatusCode: http.StatusServiceUnavailable})
+	client, buf := newLoggedClient(t, srv,
+		neatlogic.WithSensitiveAttrs("root_password"),
+		neatlogic.WithRetryPolicy(neatlogic.RetryPolicy{MaxAttempts: 3, RetryableStatus: []int{http.StatusServiceUnavailable}}),
+	)
+
+	saveAndGet(t, client)
+	logged := buf.String()
+	if strings.Contains(logged, "hunter2") || strings.Contains(logged, "web-01") {
+		t.Errorf("attribute values logged:\n%s", logged)
+	}
+	if got := strings.Count(logged, "cannot resolve sensitive attributes"); got != 1 {
+		t.Errorf("lookup warnings = %d, want 1:\n%s", got, logged)
+	}
+	// The failed lookup is neither retried nor repeated for later bodies
+	if got := srv.Requests(neatlogictest.PathCiListAttr); got != 1 {
+		t.Errorf("attribute list requests = %d, want 1", got)
+	}
+}
+
+func TestLoggingLookupBypassesMiddlewares(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	srv.SetCiAttrs(1, []neatlogic.CiAttr{{ID: 7, CiId: 1, Name: "root_password"}})
+	var paths recorder
+	client, buf := newLoggedClient(t, srv,
+		neatlogic.WithSensitiveAttrs("root_password"),
+		neatlogic.WithBeforeRequest(func(req *http.Request) error {
+			paths.add(strings.TrimPrefix(req.URL.Path, "/"+srv.Tenant))
+			return nil
+		}),
+	)
+
+	if _, err := client.SaveCientity(secretHost); err != nil {
+		t.Fatal(err)
+	}
+	if got := paths.String(); got != neatlogictest.PathCientitySave {
+		t.Errorf("requests seen by the hook = %q, want only the save", got)
+	}
+	if logged := buf.String(); strings.Contains(logged, "listattr") {
+		t.Errorf("lookup logged:\n%s", logged)
+	}
+	if got := srv.Requests(neatlogictest.PathCiListAttr); got != 1 {
+		t.Errorf("attribute list requests = %d, want 1", got)
+	}
+}
+
+func TestLoggingMiddlewareRedactsWithoutClient(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client, err := srv.NewClient(neatlogic.WithMiddleware(neatlogic.LoggingMiddleware(logger, "root_password")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saveAndGet(t, client)
+	logged := buf.String()
+	if strings.Contains(logged, "hunter2") {
+		t.Errorf("secret logged:\n%s", logged)
+	}
+	if !strings.Contains(logged, "[REDACTED]") {
+		t.Errorf("nothing redacted:\n%s", logged)
+	}
+}
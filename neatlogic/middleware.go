@@ -0,0 +1,203 @@
+package neatlogic
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/hejingwen098/neatapi/auth"
+)
+
+// Doer sends HTTP requests. *http.Client and *NeatClient satisfy it.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts a function to the Doer interface.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the Doer sending a request, e.g. to add headers, record metrics or
+// inspect responses. A middleware may send the request several times; it must close
+// the body of every response it does not return.
+type Middleware func(next Doer) Doer
+
+// BeforeRequestFunc is called right before every attempt of a request is sent,
+// after authentication. Returning an error aborts the attempt.
+type BeforeRequestFunc func(req *http.Request) error
+
+// AfterResponseFunc is called with every response received, before its body is read.
+// Returning an error aborts the request; the response body is closed.
+type AfterResponseFunc func(resp *http.Response) error
+
+// WithMiddleware appends middlewares to the chain of the client.
+// Every attempt of a request passes through the retry policy, the authenticator and the
+// logger (see WithLogger) first, then through the middlewares in the order given, the first
+// one being the outermost, then through the hooks, and is finally sent by the HTTP client.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(o *clientOptions) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// WithBeforeRequest adds a hook called right before every attempt of a request is sent,
+// e.g. to add tenant, tracing or audit headers. Hooks are called in the order they were added.
+func WithBeforeRequest(hook BeforeRequestFunc) Option {
+	return WithMiddleware(BeforeRequest(hook))
+}
+
+// WithAfterResponse adds a hook called with every response received, e.g. to inspect
+// response headers. Hooks are called in the order they were added.
+func WithAfterResponse(hook AfterResponseFunc) Option {
+	return WithMiddleware(AfterResponse(hook))
+}
+
+// Chain returns a Doer sending requests through middlewares and then doer.
+// The first middleware is the outermost one.
+//
+// Parameters:
+//   - doer: The Doer sending the requests in the end, e.g. an *http.Client
+//   - middlewares: Middlewares to apply, outermost first
+//
+// Returns:
+//   - Doer: The composed Doer
+func Chain(doer Doer, middlewares ...Middleware) Doer {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		doer = middlewares[i](doer)
+	}
+	return doer
+}
+
+// BeforeRequest returns a middleware calling hook before a request is passed on.
+func BeforeRequest(hook BeforeRequestFunc) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if err := hook(req); err != nil {
+				return nil, err
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+// AfterResponse returns a middleware calling hook with every response received from the next Doer.
+func AfterResponse(hook AfterResponseFunc) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			if err := hook(resp); err != nil {
+				discardResponse(resp)
+				return nil, err
+			}
+			return resp, nil
+		})
+	}
+}
+
+// RetryMiddleware returns a middleware retrying network errors and responses with
+// a status code listed by policy. Writes are only retried if the server cannot have
+// processed them (see Idempotent), so a retry never saves a cientity twice.
+// It waits between attempts as policy describes, honoring Retry-After, and gives up
+// as soon as the context of the request is done.
+// Requests with a body must set GetBody, as http.NewRequest does.
+func RetryMiddleware(policy RetryPolicy) Middleware {
+	return func(next Doer) Doer {
+		if policy.MaxAttempts < 2 {
+			return next
+		}
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.Do(req)
+			for attempt := 2; attempt <= policy.MaxAttempts && policy.retryable(req, resp, err); attempt++ {
+				var retryAfter string
+				if resp != nil {
+					retryAfter = resp.Header.Get("Retry-After")
+					discardResponse(resp)
+				}
+				if err := sleepCtx(req.Context(), policy.backoff(attempt-1, parseRetryAfter(retryAfter))); err != nil {
+					return nil, err
+				}
+				retry, rewindErr := rewindRequest(req)
+				if rewindErr != nil {
+					return nil, rewindErr
+				}
+				resp, err = next.Do(retry.WithContext(withAttempt(retry.Context(), attempt)))
+			}
+			return resp, err
+		})
+	}
+}
+
+// AuthMiddleware returns a middleware authenticating every request with authenticator.
+// If the server rejects a request with 401, the authenticator may renew its credentials
+// (password authentication logs in again), and the request is sent once more.
+func AuthMiddleware(authenticator auth.Authenticator) Middleware {
+	return authMiddleware(authenticator, nil)
+}
+
+// authMiddleware is AuthMiddleware, calling refreshed after the authenticator renewed its credentials.
+func authMiddleware(authenticator auth.Authenticator, refreshed func()) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if err := authenticator.Authenticate(req.Context(), req); err != nil {
+				return nil, err
+			}
+			resp, err := next.Do(req)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+
+			// Credentials expired or were rejected: let the authenticator renew them and retry once
+			retryable, err := authenticator.Refresh(req.Context(), req)
+			if err != nil {
+				discardResponse(resp)
+				return nil, err
+			}
+			if !retryable {
+				return resp, nil
+			}
+			discardResponse(resp)
+			if refreshed != nil {
+				refreshed()
+			}
+			retry, err := rewindRequest(req)
+			if err != nil {
+				return nil, err
+			}
+			if err := authenticator.Authenticate(retry.Context(), retry); err != nil {
+				return nil, err
+			}
+			return next.Do(retry)
+		})
+	}
+}
+
+// rewindRequest returns a copy of req with a fresh body so it can be sent again.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return retry, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("request body cannot be replayed")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	retry.Body = body
+	return retry, nil
+}
+
+// discardResponse drains and closes the body of a response that is not returned,
+// so that its connection can be reused.
+func discardResponse(resp *http.Response) {
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
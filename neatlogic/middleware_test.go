@@ -0,0 +1,199 @@
+package neatlogic_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+// recorder records the steps requests pass, in order.
+type recorder struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (r *recorder) add(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, step)
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.steps, " ")
+}
+
+// middleware returns a middleware recording name and whether the request was authenticated.
+func (r *recorder) middleware(name string) neatlogic.Middleware {
+	return func(next neatlogic.Doer) neatlogic.Doer {
+		return neatlogic.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			step := name
+			if req.Header.Get("Authorization") == "" {
+				step += "(unauthenticated)"
+			}
+			r.add(step)
+			return next.Do(req)
+		})
+	}
+}
+
+// bodyRecorder is a middleware recording the JSON bodies of requests by endpoint.
+type bodyRecorder struct {
+	mu     sync.Mutex
+	bodies map[string][]map[string]interface{}
+}
+
+func (b *bodyRecorder) middleware(next neatlogic.Doer) neatlogic.Doer {
+	return neatlogic.DoerFunc(func(req *http.Request) (*http.Response, error) {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		var body map[string]interface{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, err
+		}
+		_, endpoint, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+		b.mu.Lock()
+		if b.bodies == nil {
+			b.bodies = map[string][]map[string]interface{}{}
+		}
+		b.bodies["/"+endpoint] = append(b.bodies["/"+endpoint], body)
+		b.mu.Unlock()
+		return next.Do(req)
+	})
+}
+
+// only returns the body of the single request sent to endpoint, one of the neatlogictest Path constants.
+func (b *bodyRecorder) only(t *testing.T, endpoint string) map[string]interface{} {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.bodies[endpoint]) != 1 {
+		t.Fatalf("sent %d requests to %s, want 1", len(b.bodies[endpoint]), endpoint)
+	}
+	return b.bodies[endpoint][0]
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: "web-01"})
+	var steps recorder
+	client, err := srv.NewClient(
+		neatlogic.WithRetryPolicy(neatlogic.RetryPolicy{
+			MaxAttempts:     2,
+			InitialBackoff:  time.Millisecond,
+			RetryableStatus: []int{http.StatusServiceUnavailable},
+		}),
+		neatlogic.WithMiddleware(steps.middleware("mw1"), steps.middleware("mw2")),
+		neatlogic.WithBeforeRequest(func(req *http.Request) error {
+			steps.add("before")
+			return nil
+		}),
+		neatlogic.WithAfterResponse(func(resp *http.Response) error {
+			steps.add("after:" + strconv.Itoa(resp.StatusCode))
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.InjectFault(neatlogictest.PathCientitySearch, neatlogictest.Fault{StatusCode: http.StatusServiceUnavailable, Count: 1})
+	if _, err := client.GetAllCientity(1); err != nil {
+		t.Fatal(err)
+	}
+	// Every attempt passes the whole chain, authenticated before the middlewares
+	want := "mw1 mw2 before after:503 mw1 mw2 before after:200"
+	if got := steps.String(); got != want {
+		t.Errorf("steps = %q, want %q", got, want)
+	}
+}
+
+func TestBeforeRequestAborts(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	errDenied := errors.New("denied")
+	client, err := srv.NewClient(neatlogic.WithBeforeRequest(func(req *http.Request) error {
+		return errDenied
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetAllCientity(1); !errors.Is(err, errDenied) {
+		t.Fatalf("err = %v, want the hook's error", err)
+	}
+	if got := srv.Requests(neatlogictest.PathCientitySearch); got != 0 {
+		t.Errorf("search requests = %d, want 0", got)
+	}
+}
+
+func TestAfterResponseInspectsHeaders(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	var contentType string
+	errRejected := errors.New("rejected")
+	reject := false
+	client, err := srv.NewClient(neatlogic.WithAfterResponse(func(resp *http.Response) error {
+		contentType = resp.Header.Get("Content-Type")
+		if reject {
+			return errRejected
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetAllCientity(1); err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+	reject = true
+	if _, err := client.GetAllCientity(1); !errors.Is(err, errRejected) {
+		t.Errorf("err = %v, want the hook's error", err)
+	}
+}
+
+func TestDoSendsThroughChain(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: "web-01"})
+	var steps recorder
+	client, err := srv.NewClient(neatlogic.WithMiddleware(steps.middleware("mw")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", client.NeatlogicUri+neatlogictest.PathCientitySearch, strings.NewReader(`{"ciId":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := steps.String(); got != "mw" {
+		t.Errorf("steps = %q, want an authenticated pass through mw", got)
+	}
+}
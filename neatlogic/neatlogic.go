@@ -5,14 +5,15 @@ package neatlogic
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 
 	"github.com/hejingwen098/neatapi/auth"
-	"github.com/hejingwen098/neatapi/common"
 )
 
 // LRequest represents the login request structure for NeatLogic authentication.
@@ -39,8 +40,25 @@ type NeatClient struct {
 	Client *http.Client
 	// NeatlogicUri is the base URL for the NeatLogic API.
 	NeatlogicUri string
-	// JwtToken is the authentication token for API requests.
+	// JwtToken is the bearer token of password and token authentication.
+	// It is replaced when SendRequest logs in again after the token expired.
+	// Clients created without New send it as bearer token.
 	JwtToken string
+
+	// authenticator authenticates every request; nil means JwtToken is sent as bearer token.
+	authenticator auth.Authenticator
+	// tokenMu guards JwtToken.
+	tokenMu sync.Mutex
+	// retryPolicy controls retries of transient failures.
+	retryPolicy RetryPolicy
+	// prefetchWorkers is the number of pages fetched concurrently when collecting search results.
+	prefetchWorkers int
+	// batchPolicy controls chunking and concurrency of batch imports.
+	batchPolicy BatchPolicy
+	// middlewares wrap every attempt of a request, after retries and authentication.
+	middlewares []Middleware
+	// logging logs every attempt of a request; nil disables logging.
+	logging Middleware
 }
 
 // CRequestBody represents the request body structure for searching CMDB entities with filters.
@@ -62,10 +80,12 @@ type CRequestBody struct {
 	// CurrentPage indicates the current page number for pagination.
 	CurrentPage int `json:"currentPage"`
 	// AttrFilterList contains attribute filters for the search.
+	// See the FilterKey constants for the expected keys, or build it with Query.
 	AttrFilterList []map[string]interface{} `json:"attrFilterList"`
 	// GlobalAttrFilterList contains global attribute filters for the search.
 	GlobalAttrFilterList []map[string]interface{} `json:"globalAttrFilterList"`
 	// RelFilterList contains relationship filters for the search.
+	// See the FilterKey constants for the expected keys, or build it with Query.
 	RelFilterList []map[string]interface{} `json:"relFilterList"`
 	// Keyword is the search keyword for filtering results.
 	Keyword string `json:"keyword"`
@@ -187,22 +207,27 @@ type AReturn struct {
 	ID int64 `json:"id"`
 }
 
-// NewNeatClient creates a new NeatClient instance with default configuration.
+// NewNeatClient creates a new NeatClient instance from ./config.yml.
 // It performs authentication and initializes the client with the JWT token.
 //
 // Returns:
 //   - *NeatClient: A new client instance ready to make API calls
-//   - Panics if authentication fails
+//   - Panics if the configuration cannot be loaded or authentication fails
 func NewNeatClient() *NeatClient {
-	token, err := auth.Login()
-	if err != nil {
-		panic(err)
-	}
-	return &NeatClient{
-		Client:       &http.Client{},
-		NeatlogicUri: common.NeatlogicUri,
-		JwtToken:     token,
-	}
+	return NewNeatClientCtx(context.Background())
+}
+
+// NewNeatClientCtx creates a new NeatClient instance from ./config.yml.
+// The login request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the login request
+//
+// Returns:
+//   - *NeatClient: A new client instance ready to make API calls
+//   - Panics if the configuration cannot be loaded or authentication fails
+func NewNeatClientCtx(ctx context.Context) *NeatClient {
+	return NewNeatClientWithConfigPathCtx(ctx, "./config.yml")
 }
 
 // NewNeatClientWithConfigPath creates a new NeatClient instance with a custom configuration file path.
@@ -213,17 +238,27 @@ func NewNeatClient() *NeatClient {
 //
 // Returns:
 //   - *NeatClient: A new client instance ready to make API calls
-//   - Panics if authentication fails
+//   - Panics if the configuration cannot be loaded or authentication fails
 func NewNeatClientWithConfigPath(configPath string) *NeatClient {
-	token, err := auth.LoginWithConfigPath(configPath)
+	return NewNeatClientWithConfigPathCtx(context.Background(), configPath)
+}
+
+// NewNeatClientWithConfigPathCtx creates a new NeatClient instance with a custom configuration file path.
+// The login request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the login request
+//   - configPath: Path to the configuration file to use
+//
+// Returns:
+//   - *NeatClient: A new client instance ready to make API calls
+//   - Panics if the configuration cannot be loaded or authentication fails
+func NewNeatClientWithConfigPathCtx(ctx context.Context, configPath string) *NeatClient {
+	client, err := NewCtx(ctx, WithConfigPath(configPath))
 	if err != nil {
 		panic(err)
 	}
-	return &NeatClient{
-		Client:       &http.Client{},
-		NeatlogicUri: common.NeatlogicUri,
-		JwtToken:     token,
-	}
+	return client
 }
 
 // GetAllCientity retrieves all CMDB entities for a given configuration item ID.
@@ -236,41 +271,22 @@ func NewNeatClientWithConfigPath(configPath string) *NeatClient {
 //   - []TbodyList: A slice of all CMDB entities found
 //   - error: An error if the operation fails
 func (c *NeatClient) GetAllCientity(ciId int64) ([]TbodyList, error) {
-	var allCientity []TbodyList
-	currentPage := 1
-
-	for {
-		url := fmt.Sprintf("%s/api/rest/cmdb/cientity/search", c.NeatlogicUri)
-		reqbody := CRequest{
-			CiId:        ciId,
-			CurrentPage: currentPage,
-			PageSize:    100,
-		}
-		jsonData, err := json.Marshal(reqbody)
-		if err != nil {
-			return nil, err
-		}
-
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-		if err != nil {
-			return nil, err
-		}
+	return c.GetAllCientityCtx(context.Background(), ciId)
+}
 
-		resp, err := c.SendRequest(req)
-		if err != nil {
-			return nil, err
-		}
-		var respBody CResponse
-		if err := json.Unmarshal(resp, &respBody); err != nil {
-			return nil, err
-		}
-		allCientity = append(allCientity, respBody.CReturn.TbodyList...)
-		if currentPage >= respBody.CReturn.PageCount {
-			break
-		}
-		currentPage++
-	}
-	return allCientity, nil
+// GetAllCientityCtx retrieves all CMDB entities for a given configuration item ID.
+// Every page request is bound to ctx and pagination stops as soon as ctx is done.
+// Pages after the first are fetched concurrently, see WithPrefetchWorkers.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the search
+//   - ciId: The configuration item ID to search for
+//
+// Returns:
+//   - []TbodyList: A slice of all CMDB entities found
+//   - error: An error if the operation fails or ctx is done
+func (c *NeatClient) GetAllCientityCtx(ctx context.Context, ciId int64) ([]TbodyList, error) {
+	return c.fetchAllPages(ctx, allCientityBody(ciId))
 }
 
 // SearchCientityByFilter retrieves CMDB entities based on filter criteria.
@@ -283,36 +299,22 @@ func (c *NeatClient) GetAllCientity(ciId int64) ([]TbodyList, error) {
 //   - []TbodyList: A slice of CMDB entities that match the filter criteria
 //   - error: An error if the operation fails
 func (c *NeatClient) SearchCientityByFilter(reqbody CRequestBody) ([]TbodyList, error) {
-	var allCientity []TbodyList
-	currentPage := 1
-
-	for {
-		url := fmt.Sprintf("%s/api/rest/cmdb/cientity/search", c.NeatlogicUri)
-		reqbody.CurrentPage = currentPage
-		jsonData, err := json.Marshal(reqbody)
-		if err != nil {
-			return nil, err
-		}
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-		if err != nil {
-			return nil, err
-		}
-		resp, err := c.SendRequest(req)
-		if err != nil {
-			return nil, err
-		}
-		var respBody CResponse
-		if err := json.Unmarshal(resp, &respBody); err != nil {
-			return nil, err
-		}
-		allCientity = append(allCientity, respBody.CReturn.TbodyList...)
-		if currentPage >= respBody.CReturn.PageCount {
-			break
-		}
-		currentPage++
-	}
+	return c.SearchCientityByFilterCtx(context.Background(), reqbody)
+}
 
-	return allCientity, nil
+// SearchCientityByFilterCtx retrieves CMDB entities based on filter criteria.
+// Every page request is bound to ctx and pagination stops as soon as ctx is done.
+// Pages after the first are fetched concurrently, see WithPrefetchWorkers.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the search
+//   - reqbody: The request body containing filter criteria
+//
+// Returns:
+//   - []TbodyList: A slice of CMDB entities that match the filter criteria
+//   - error: An error if the operation fails or ctx is done
+func (c *NeatClient) SearchCientityByFilterCtx(ctx context.Context, reqbody CRequestBody) ([]TbodyList, error) {
+	return c.fetchAllPages(ctx, filterBody(reqbody))
 }
 
 // SearchCientityByKeyword searches for CMDB entities using a keyword and configuration item ID.
@@ -326,42 +328,23 @@ func (c *NeatClient) SearchCientityByFilter(reqbody CRequestBody) ([]TbodyList,
 //   - []TbodyList: A slice of CMDB entities that match the search criteria
 //   - error: An error if the operation fails
 func (c *NeatClient) SearchCientityByKeyword(ciId int64, keyword string) ([]TbodyList, error) {
-	var allCientity []TbodyList
-	currentPage := 1
-
-	for {
-		url := fmt.Sprintf("%s/api/rest/cmdb/cientity/search", c.NeatlogicUri)
-
-		// Build request body
-		reqbody := CRequest{
-			CiId:        ciId,
-			CurrentPage: currentPage,
-			Keyword:     keyword,
-		}
-		jsonData, err := json.Marshal(reqbody)
-		if err != nil {
-			return nil, err
-		}
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-		if err != nil {
-			return nil, err
-		}
-		resp, err := c.SendRequest(req)
-		if err != nil {
-			return nil, err
-		}
-		var respBody CResponse
-		if err := json.Unmarshal(resp, &respBody); err != nil {
-			return nil, err
-		}
-		allCientity = append(allCientity, respBody.CReturn.TbodyList...)
-		if currentPage >= respBody.CReturn.PageCount {
-			break
-		}
-		currentPage++
-	}
+	return c.SearchCientityByKeywordCtx(context.Background(), ciId, keyword)
+}
 
-	return allCientity, nil
+// SearchCientityByKeywordCtx searches for CMDB entities using a keyword and configuration item ID.
+// Every page request is bound to ctx and pagination stops as soon as ctx is done.
+// Pages after the first are fetched concurrently, see WithPrefetchWorkers.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the search
+//   - ciId: The configuration item ID to search within
+//   - keyword: The keyword to search for
+//
+// Returns:
+//   - []TbodyList: A slice of CMDB entities that match the search criteria
+//   - error: An error if the operation fails or ctx is done
+func (c *NeatClient) SearchCientityByKeywordCtx(ctx context.Context, ciId int64, keyword string) ([]TbodyList, error) {
+	return c.fetchAllPages(ctx, keywordBody(ciId, keyword))
 }
 
 // GetCientity retrieves a specific CMDB entity by its configuration item ID and entity ID.
@@ -375,6 +358,21 @@ func (c *NeatClient) SearchCientityByKeyword(ciId int64, keyword string) ([]Tbod
 //   - TbodyList: The requested CMDB entity
 //   - error: An error if the operation fails
 func (c *NeatClient) GetCientity(ciId int64, ciEntityId int64) (TbodyList, error) {
+	return c.GetCientityCtx(context.Background(), ciId, ciEntityId)
+}
+
+// GetCientityCtx retrieves a specific CMDB entity by its configuration item ID and entity ID.
+// The request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the request
+//   - ciId: The configuration item ID
+//   - ciEntityId: The specific entity ID to retrieve
+//
+// Returns:
+//   - TbodyList: The requested CMDB entity
+//   - error: An error if the operation fails
+func (c *NeatClient) GetCientityCtx(ctx context.Context, ciId int64, ciEntityId int64) (TbodyList, error) {
 	// Do not limit RelEntity and AttrEntity
 	url := fmt.Sprintf("%s/api/rest/cmdb/cientity/get", c.NeatlogicUri)
 
@@ -389,7 +387,7 @@ func (c *NeatClient) GetCientity(ciId int64, ciEntityId int64) (TbodyList, error
 	if err != nil {
 		return TbodyList{}, err
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return TbodyList{}, err
 	}
@@ -404,8 +402,14 @@ func (c *NeatClient) GetCientity(ciId int64, ciEntityId int64) (TbodyList, error
 	return respBody.GetcientityReturn, nil
 }
 
-// SendRequest sends an HTTP request with JWT authentication headers.
+// SendRequest sends an HTTP request authenticated by the client's Authenticator.
 // It adds the required headers and processes the response.
+// The request is bound to the context already attached to req.
+// If the server rejects the request with 401, the Authenticator may renew its credentials
+// (password authentication logs in again), and the request is retried once.
+// Concurrent callers share a single refresh.
+// Transient failures are retried according to the client's RetryPolicy.
+// Every attempt passes through the middlewares and hooks of the client, see WithMiddleware.
 //
 // Parameters:
 //   - req: The HTTP request to send
@@ -414,11 +418,7 @@ func (c *NeatClient) GetCientity(ciId int64, ciEntityId int64) (TbodyList, error
 //   - []byte: The response body as bytes
 //   - error: An error if the operation fails
 func (c *NeatClient) SendRequest(req *http.Request) ([]byte, error) {
-	// Set JWT authentication headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.JwtToken)
-
-	resp, err := c.Client.Do(req)
+	resp, err := c.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -432,24 +432,131 @@ func (c *NeatClient) SendRequest(req *http.Request) ([]byte, error) {
 	return respBody, nil
 }
 
+// Do sends an HTTP request through the middleware chain of the client and returns the raw response,
+// like SendRequest but without checking the status code or reading the body.
+// The caller must close the response body.
+//
+// Parameters:
+//   - req: The HTTP request to send
+//
+// Returns:
+//   - *http.Response: The response of the last attempt
+//   - error: An error if no response was received
+func (c *NeatClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.doer().Do(req)
+}
+
+// doer returns the middleware chain of the client: retries, authentication, logging,
+// the configured middlewares and finally the HTTP client.
+func (c *NeatClient) doer() Doer {
+	authenticator := c.auth()
+	middlewares := []Middleware{
+		RetryMiddleware(c.retryPolicy),
+		authMiddleware(authenticator, func() { c.syncToken(authenticator) }),
+	}
+	if c.logging != nil {
+		middlewares = append(middlewares, c.logging)
+	}
+	return Chain(c.Client, append(middlewares, c.middlewares...)...)
+}
+
+// auth returns the authenticator of the client.
+func (c *NeatClient) auth() auth.Authenticator {
+	if c.authenticator != nil {
+		return c.authenticator
+	}
+	return auth.TokenAuth{Token: c.token()}
+}
+
+// token returns the current JWT token.
+func (c *NeatClient) token() string {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.JwtToken
+}
+
+// syncToken copies the token of a password authenticator to JwtToken after it logged in again.
+func (c *NeatClient) syncToken(authenticator auth.Authenticator) {
+	password, ok := authenticator.(*auth.PasswordAuth)
+	if !ok {
+		return
+	}
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.JwtToken = password.Token()
+}
+
+// SendRequestCtx sends an HTTP request with JWT authentication headers, bound to ctx.
+// It replaces any context already attached to req.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the request
+//   - req: The HTTP request to send
+//
+// Returns:
+//   - []byte: The response body as bytes
+//   - error: An error if the operation fails
+func (c *NeatClient) SendRequestCtx(ctx context.Context, req *http.Request) ([]byte, error) {
+	return c.SendRequest(req.WithContext(ctx))
+}
+
+// postJSON posts reqbody as JSON to the API at path and decodes the response into respBody.
+// A nil respBody discards the response.
+func (c *NeatClient) postJSON(ctx context.Context, path string, reqbody interface{}, respBody interface{}) error {
+	url := c.NeatlogicUri + path
+	jsonData, err := json.Marshal(reqbody)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	resp, err := c.SendRequest(req)
+	if err != nil {
+		return err
+	}
+	if respBody == nil {
+		return nil
+	}
+	return json.Unmarshal(resp, respBody)
+}
+
 // ParseResourceResponse parses an HTTP response and returns the response body.
-// It checks the status code and returns an error if the request was not successful.
+// It returns an *APIError if the status code is not OK or if the NeatLogic
+// envelope in the body reports Status "ERROR".
 //
 // Parameters:
 //   - resp: The HTTP response to parse
 //
 // Returns:
 //   - []byte: The response body as bytes
-//   - error: An error if the operation fails or status code is not OK
+//   - error: An error if the operation fails, the status code is not OK or the API reports an error
 func ParseResourceResponse(resp *http.Response) ([]byte, error) {
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed with status code: %d", resp.StatusCode)
-	}
-
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
+
+	// The body is not always a NeatLogic envelope (e.g. proxy error pages), so decoding is best effort
+	var env envelope
+	_ = json.Unmarshal(respBody, &env)
+	if resp.StatusCode != http.StatusOK || env.Status == "ERROR" {
+		apiErr := &APIError{
+			StatusCode: resp.StatusCode,
+			Status:     env.Status,
+			Message:    env.Message,
+			TimeCost:   env.TimeCost,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+		if resp.Request != nil && resp.Request.URL != nil {
+			apiErr.Endpoint = resp.Request.URL.Path
+		}
+		return nil, apiErr
+	}
 	return respBody, nil
 }
 
@@ -464,6 +571,21 @@ func ParseResourceResponse(resp *http.Response) ([]byte, error) {
 //   - []AReturn: A slice of attribute search results
 //   - error: An error if the operation fails
 func (c *NeatClient) SearchTargetAttr(reqbody CRequestBody, attrId string) ([]AReturn, error) {
+	return c.SearchTargetAttrCtx(context.Background(), reqbody, attrId)
+}
+
+// SearchTargetAttrCtx searches for target attributes based on a request body and attribute ID.
+// The request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the request
+//   - reqbody: The request body containing search criteria (keyword is typically required)
+//   - attrId: The attribute ID to search for
+//
+// Returns:
+//   - []AReturn: A slice of attribute search results
+//   - error: An error if the operation fails
+func (c *NeatClient) SearchTargetAttrCtx(ctx context.Context, reqbody CRequestBody, attrId string) ([]AReturn, error) {
 	// This function is used to search for target attributes
 	// It requires a request body (containing keyword) and an attribute ID
 	apiurl := fmt.Sprintf("%s/api/rest/cmdb/attr/targetci/search", c.NeatlogicUri)
@@ -473,7 +595,7 @@ func (c *NeatClient) SearchTargetAttr(reqbody CRequestBody, attrId string) ([]AR
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest("POST", apiurl+"?"+parmas.Encode(), bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiurl+"?"+parmas.Encode(), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
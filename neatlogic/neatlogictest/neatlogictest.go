@@ -0,0 +1,692 @@
+// Package neatlogictest provides an in-memory NeatLogic server for testing code built on
+// neatlogic.NeatClient without a live NeatLogic instance.
+//
+//	srv := neatlogictest.NewServer()
+//	defer srv.Close()
+//	srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: "web-01"})
+//	client, err := srv.NewClient()
+//	// ...
+//	entities, err := client.GetAllCientity(1)
+//
+// The server implements login, cientity search (with paging and keyword matching),
+// cientity get, save, batch save and delete, CI search and get, CI attribute and relation lists and
+// target attribute search. Faults can be injected per endpoint to exercise error handling,
+// retries and token refresh.
+package neatlogictest
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+)
+
+// Endpoints served by Server, relative to the tenant. They are the keys of InjectFault and Requests.
+const (
+	PathLogin          = "/login/check"
+	PathCientitySearch = "/api/rest/cmdb/cientity/search"
+	PathCientityGet    = "/api/rest/cmdb/cientity/get"
+	PathCientitySave   = "/api/rest/cmdb/cientity/save"
+	PathCientityBatch  = "/api/rest/cmdb/cientity/batchsave"
+	PathCientityDelete = "/api/rest/cmdb/cientity/delete"
+	PathCiSearch       = "/api/rest/cmdb/ci/search"
+	PathCiGet          = "/api/rest/cmdb/ci/get"
+	PathCiListAttr     = "/api/rest/cmdb/ci/listattr"
+	PathCiListRel      = "/api/rest/cmdb/ci/listrel"
+	PathTargetCiSearch = "/api/rest/cmdb/attr/targetci/search"
+)
+
+// defaultSearchPageSize is the page size of cientity and CI searches that do not set one.
+const defaultSearchPageSize = 20
+
+// Defaults of a Server created by NewServer.
+const (
+	// DefaultTenant is the tenant the server is mounted under.
+	DefaultTenant = "demo"
+	// DefaultUsername is the user the server accepts, and NewClient logs in as.
+	DefaultUsername = "admin"
+	// DefaultPassword is the password of DefaultUsername.
+	DefaultPassword = "password"
+)
+
+// Fault describes an error response injected with InjectFault.
+type Fault struct {
+	// StatusCode is the HTTP status code of the response. Zero means 500.
+	StatusCode int
+	// Message is the message of the ERROR envelope in the response body.
+	Message string
+	// RetryAfter sets the Retry-After header, in whole seconds, if positive.
+	RetryAfter time.Duration
+	// Count is the number of requests that fail before the endpoint recovers.
+	// Zero fails every request until ClearFaults is called.
+	Count int
+}
+
+// Server is a fake NeatLogic server backed by in-memory data.
+// It is safe for concurrent use.
+type Server struct {
+	// Server is the underlying HTTP test server; its URL is the base URL without tenant.
+	*httptest.Server
+	// Tenant is the tenant the API is mounted under.
+	Tenant string
+
+	mu         sync.Mutex
+	users      map[string]string
+	tokens     map[string]bool
+	logins     int
+	cientities []neatlogic.TbodyList
+	nextId     int64
+	nextTxId   int64
+	cis        []neatlogic.Ci
+	nextCiId   int64
+	attrs      map[int64][]neatlogic.CiAttr
+	rels       map[int64][]neatlogic.CiRel
+	targets    map[int64][]neatlogic.AReturn
+	faults     map[string]*Fault
+	requests   map[string]int
+}
+
+// NewServer starts a server for DefaultTenant accepting DefaultUsername with DefaultPassword.
+// The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		Tenant:   DefaultTenant,
+		users:    map[string]string{DefaultUsername: DefaultPassword},
+		tokens:   map[string]bool{},
+		nextId:   1,
+		nextTxId: 1,
+		nextCiId: 1,
+		attrs:    map[int64][]neatlogic.CiAttr{},
+		rels:     map[int64][]neatlogic.CiRel{},
+		targets:  map[int64][]neatlogic.AReturn{},
+		faults:   map[string]*Fault{},
+		requests: map[string]int{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// NewClient creates a NeatClient logged in to the server as DefaultUsername.
+// opts are applied after the server's base URL, tenant, credentials and HTTP client,
+// so they can override them.
+//
+// Parameters:
+//   - opts: Additional client options, e.g. neatlogic.WithRetryPolicy
+//
+// Returns:
+//   - *neatlogic.NeatClient: A client connected to the server
+//   - error: An error if the login fails
+func (s *Server) NewClient(opts ...neatlogic.Option) (*neatlogic.NeatClient, error) {
+	base := []neatlogic.Option{
+		neatlogic.WithBaseURL(s.URL),
+		neatlogic.WithTenant(s.Tenant),
+		neatlogic.WithCredentials(DefaultUsername, DefaultPassword),
+		neatlogic.WithHTTPClient(s.Server.Client()),
+	}
+	return neatlogic.New(append(base, opts...)...)
+}
+
+// AddUser adds a user that can log in, or changes the password of an existing one.
+func (s *Server) AddUser(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = password
+}
+
+// AddCientity stores a cientity and returns its ID. A zero ID is replaced by a generated one;
+// a cientity with the ID of a stored one replaces it.
+// AttrEntityData and RelEntityData are returned to clients as given, keyed like NeatLogic
+// does (attr_<id>, rel<direction>_<id>).
+func (s *Server) AddCientity(entity neatlogic.TbodyList) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entity.ID == 0 {
+		entity.ID = s.nextId
+	}
+	s.nextId = max(s.nextId, entity.ID+1)
+	for i := range s.cientities {
+		if s.cientities[i].ID == entity.ID {
+			s.cientities[i] = entity
+			return entity.ID
+		}
+	}
+	s.cientities = append(s.cientities, entity)
+	return entity.ID
+}
+
+// Cientity returns the stored cientity with the given ID, e.g. to check a save.
+func (s *Server) Cientity(id int64) (neatlogic.TbodyList, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entity := range s.cientities {
+		if entity.ID == id {
+			return entity, true
+		}
+	}
+	return neatlogic.TbodyList{}, false
+}
+
+// AddCi stores a configuration item and returns its ID. A zero ID is replaced by a generated one;
+// a configuration item with the ID of a stored one replaces it.
+// Attrs and Rels, if given, are served as if set with SetCiAttrs and SetCiRels.
+func (s *Server) AddCi(ci neatlogic.Ci) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ci.ID == 0 {
+		ci.ID = s.nextCiId
+	}
+	s.nextCiId = max(s.nextCiId, ci.ID+1)
+	if ci.Attrs != nil {
+		s.attrs[ci.ID] = ci.Attrs
+	}
+	if ci.Rels != nil {
+		s.rels[ci.ID] = ci.Rels
+	}
+	ci.Attrs, ci.Rels = nil, nil
+	for i := range s.cis {
+		if s.cis[i].ID == ci.ID {
+			s.cis[i] = ci
+			return ci.ID
+		}
+	}
+	s.cis = append(s.cis, ci)
+	return ci.ID
+}
+
+// SetCiAttrs sets the attribute definitions returned for ciId.
+func (s *Server) SetCiAttrs(ciId int64, attrs []neatlogic.CiAttr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs[ciId] = attrs
+}
+
+// SetCiRels sets the relation definitions returned for ciId.
+func (s *Server) SetCiRels(ciId int64, rels []neatlogic.CiRel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rels[ciId] = rels
+}
+
+// SetTargetCis sets the cientities offered by the target attribute search for attrId.
+func (s *Server) SetTargetCis(attrId int64, targets []neatlogic.AReturn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets[attrId] = targets
+}
+
+// InjectFault makes requests to endpoint, one of the Path constants, fail with fault.
+// It replaces a fault previously injected for the endpoint.
+func (s *Server) InjectFault(endpoint string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[endpoint] = &fault
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = map[string]*Fault{}
+}
+
+// ExpireTokens invalidates every issued token, so that the next API request is rejected
+// with 401 Unauthorized until the client logs in again.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = map[string]bool{}
+}
+
+// Requests returns the number of requests received for endpoint, including failed ones.
+func (s *Server) Requests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[endpoint]
+}
+
+// serveHTTP routes a request to the handler of its endpoint.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := strings.CutPrefix(r.URL.Path, "/"+s.Tenant)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("tenant of %s not found", r.URL.Path))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[endpoint]++
+	if s.writeFault(w, endpoint) {
+		return
+	}
+	if endpoint != PathLogin && !s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")] {
+		writeError(w, http.StatusUnauthorized, "token is invalid or expired")
+		return
+	}
+
+	switch endpoint {
+	case PathLogin:
+		s.login(w, r)
+	case PathCientitySearch:
+		s.searchCientity(w, r)
+	case PathCientityGet:
+		s.getCientity(w, r)
+	case PathCientitySave:
+		s.saveCientity(w, r)
+	case PathCientityBatch:
+		s.batchSaveCientity(w, r)
+	case PathCientityDelete:
+		s.deleteCientity(w, r)
+	case PathCiSearch:
+		s.searchCi(w, r)
+	case PathCiGet:
+		s.getCi(w, r)
+	case PathCiListAttr:
+		s.listCiAttrs(w, r)
+	case PathCiListRel:
+		s.listCiRels(w, r)
+	case PathTargetCiSearch:
+		s.searchTargetCi(w, r)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("api %s not found", endpoint))
+	}
+}
+
+// writeFault writes the fault injected for endpoint, if any, and reports whether it did.
+func (s *Server) writeFault(w http.ResponseWriter, endpoint string) bool {
+	fault, ok := s.faults[endpoint]
+	if !ok {
+		return false
+	}
+	if fault.Count > 0 {
+		fault.Count--
+		if fault.Count == 0 {
+			delete(s.faults, endpoint)
+		}
+	}
+	if fault.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(fault.RetryAfter/time.Second)))
+	}
+	statusCode := fault.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+	writeError(w, statusCode, fault.Message)
+	return true
+}
+
+// login checks the credentials and issues a token. Passwords are accepted MD5 or base64 encoded.
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID   string `json:"userid"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	password, ok := s.users[req.UserID]
+	sum := md5.Sum([]byte(password))
+	if !ok || (req.Password != "{MD5}"+hex.EncodeToString(sum[:]) &&
+		req.Password != "{BS}"+base64.StdEncoding.EncodeToString([]byte(password))) {
+		// NeatLogic reports failed logins in the body of a 200 response
+		writeError(w, http.StatusOK, "invalid username or password")
+		return
+	}
+	s.logins++
+	token := fmt.Sprintf("neatlogictest-%s-%d", req.UserID, s.logins)
+	s.tokens[token] = true
+	writeJSON(w, map[string]interface{}{"Status": "OK", "JwtToken": token})
+}
+
+// searchCientity returns a page of the cientities of a CI matching a keyword.
+// Attribute and relation filters are not evaluated.
+func (s *Server) searchCientity(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CiId        int64  `json:"ciId"`
+		Keyword     string `json:"keyword"`
+		PageSize    int    `json:"pageSize"`
+		CurrentPage int    `json:"currentPage"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = defaultSearchPageSize
+	}
+	if req.CurrentPage <= 0 {
+		req.CurrentPage = 1
+	}
+
+	var matches []neatlogic.TbodyList
+	for _, entity := range s.cientities {
+		if (req.CiId == 0 || entity.CiId == req.CiId) && matchKeyword(entity, req.Keyword) {
+			matches = append(matches, entity)
+		}
+	}
+	start := min((req.CurrentPage-1)*req.PageSize, len(matches))
+	end := min(start+req.PageSize, len(matches))
+	page := append([]neatlogic.TbodyList{}, matches[start:end]...)
+
+	writeJSON(w, map[string]interface{}{
+		"Status": "OK",
+		"Return": neatlogic.CReturn{
+			PageCount:   (len(matches) + req.PageSize - 1) / req.PageSize,
+			RowNum:      len(matches),
+			PageSize:    req.PageSize,
+			CurrentPage: req.CurrentPage,
+			TbodyList:   page,
+		},
+	})
+}
+
+// getCientity returns a single cientity. Unknown cientities are reported with 404 Not Found.
+func (s *Server) getCientity(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CiId       int64 `json:"ciId"`
+		CiEntityId int64 `json:"ciEntityId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, entity := range s.cientities {
+		if entity.ID == req.CiEntityId && (req.CiId == 0 || entity.CiId == req.CiId) {
+			writeJSON(w, map[string]interface{}{"Status": "OK", "Return": entity})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Sprintf("cientity %d not found", req.CiEntityId))
+}
+
+// saveRequest is a cientity in the body of the cientity save and batch save APIs.
+type saveRequest struct {
+	ID             int64                  `json:"id"`
+	CiId           int64                  `json:"ciId"`
+	EditMode       string                 `json:"editMode"`
+	AttrEntityData map[string]interface{} `json:"attrEntityData"`
+}
+
+// saveCientity creates or updates a cientity. In partial edit mode only the attributes
+// in the request are replaced. Relations are not stored.
+func (s *Server) saveCientity(w http.ResponseWriter, r *http.Request) {
+	var req saveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.ID != 0 && s.cientityIndex(req.ID) < 0 {
+		writeError(w, http.StatusOK, fmt.Sprintf("cientity %d not found", req.ID))
+		return
+	}
+	txId := s.nextTxId
+	s.nextTxId++
+	writeJSON(w, map[string]interface{}{"Status": "OK", "Return": s.save(req, txId, txId)})
+}
+
+// batchSaveCientity saves several cientities like saveCientity, in one transaction group.
+// If one of them cannot be saved, none is.
+func (s *Server) batchSaveCientity(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CiEntityList []saveRequest `json:"ciEntityList"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, entity := range req.CiEntityList {
+		if entity.ID != 0 && s.cientityIndex(entity.ID) < 0 {
+			// NeatLogic rejects the whole batch in the body of a 200 response
+			writeError(w, http.StatusOK, fmt.Sprintf("cientity %d not found", entity.ID))
+			return
+		}
+	}
+	groupId := s.nextTxId
+	s.nextTxId++
+	results := make([]neatlogic.SaveResult, len(req.CiEntityList))
+	for i, entity := range req.CiEntityList {
+		// Every cientity of a batch gets its own transaction
+		results[i] = s.save(entity, s.nextTxId, groupId)
+		s.nextTxId++
+	}
+	writeJSON(w, map[string]interface{}{
+		"Status": "OK",
+		"Return": neatlogic.BatchSaveReturn{TransactionGroupId: groupId, CiEntityList: results},
+	})
+}
+
+// save stores a cientity whose ID, if any, is known, and reports the change as
+// transaction txId of the transaction group groupId.
+func (s *Server) save(req saveRequest, txId, groupId int64) neatlogic.SaveResult {
+	entity := neatlogic.TbodyList{ID: s.nextId, CiId: req.CiId, AttrEntityData: map[string]interface{}{}}
+	index := -1
+	if req.ID != 0 {
+		index = s.cientityIndex(req.ID)
+		entity = s.cientities[index]
+	}
+	attrs := map[string]interface{}{}
+	if req.EditMode == "partial" {
+		for key, value := range entity.AttrEntityData {
+			attrs[key] = value
+		}
+	}
+	for key, value := range req.AttrEntityData {
+		attrs[key] = value
+	}
+	entity.AttrEntityData = attrs
+	if index < 0 {
+		s.nextId++
+		s.cientities = append(s.cientities, entity)
+	} else {
+		s.cientities[index] = entity
+	}
+	return neatlogic.SaveResult{CiEntityId: entity.ID, TransactionId: txId, TransactionGroupId: groupId}
+}
+
+// cientityIndex returns the index of the stored cientity with the given ID, or -1.
+func (s *Server) cientityIndex(id int64) int {
+	for i := range s.cientities {
+		if s.cientities[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// deleteCientity removes cientities. If one of them is unknown, none is removed.
+func (s *Server) deleteCientity(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CiEntityList []struct {
+			ID   int64 `json:"id"`
+			CiId int64 `json:"ciId"`
+		} `json:"ciEntityList"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	remove := map[int64]bool{}
+	for _, item := range req.CiEntityList {
+		found := false
+		for _, entity := range s.cientities {
+			if entity.ID == item.ID && entity.CiId == item.CiId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			writeError(w, http.StatusOK, fmt.Sprintf("cientity %d not found", item.ID))
+			return
+		}
+		remove[item.ID] = true
+	}
+	kept := s.cientities[:0]
+	for _, entity := range s.cientities {
+		if !remove[entity.ID] {
+			kept = append(kept, entity)
+		}
+	}
+	s.cientities = kept
+
+	txId := s.nextTxId
+	s.nextTxId++
+	writeJSON(w, map[string]interface{}{
+		"Status": "OK",
+		"Return": neatlogic.SaveResult{TransactionId: txId, TransactionGroupId: txId},
+	})
+}
+
+// searchCi returns a page of the configuration items whose name or label contains a keyword.
+func (s *Server) searchCi(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Keyword     string `json:"keyword"`
+		PageSize    int    `json:"pageSize"`
+		CurrentPage int    `json:"currentPage"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = defaultSearchPageSize
+	}
+	if req.CurrentPage <= 0 {
+		req.CurrentPage = 1
+	}
+
+	var matches []neatlogic.Ci
+	for _, ci := range s.cis {
+		if containsFold(ci.Name, req.Keyword) || containsFold(ci.Label, req.Keyword) {
+			matches = append(matches, ci)
+		}
+	}
+	start := min((req.CurrentPage-1)*req.PageSize, len(matches))
+	end := min(start+req.PageSize, len(matches))
+	page := append([]neatlogic.Ci{}, matches[start:end]...)
+
+	writeJSON(w, map[string]interface{}{
+		"Status": "OK",
+		"Return": neatlogic.CiListReturn{
+			PageCount: (len(matches) + req.PageSize - 1) / req.PageSize,
+			TbodyList: page,
+		},
+	})
+}
+
+// getCi returns a single configuration item without its attributes and relations.
+// Unknown configuration items are reported with 404 Not Found.
+func (s *Server) getCi(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, ci := range s.cis {
+		if ci.ID == req.ID {
+			writeJSON(w, map[string]interface{}{"Status": "OK", "Return": ci})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Sprintf("ci %d not found", req.ID))
+}
+
+// listCiAttrs returns the attribute definitions set for a CI with SetCiAttrs.
+func (s *Server) listCiAttrs(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CiId int64 `json:"ciId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	attrs := append([]neatlogic.CiAttr{}, s.attrs[req.CiId]...)
+	writeJSON(w, map[string]interface{}{"Status": "OK", "Return": attrs})
+}
+
+// listCiRels returns the relation definitions set for a CI with SetCiRels.
+func (s *Server) listCiRels(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CiId int64 `json:"ciId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rels := append([]neatlogic.CiRel{}, s.rels[req.CiId]...)
+	writeJSON(w, map[string]interface{}{"Status": "OK", "Return": rels})
+}
+
+// searchTargetCi returns the targets set for the attrId query parameter whose name contains the keyword.
+func (s *Server) searchTargetCi(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Keyword string `json:"keyword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	attrId, err := strconv.ParseInt(r.URL.Query().Get("attrId"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "attrId is not a number")
+		return
+	}
+	targets := []neatlogic.AReturn{}
+	for _, target := range s.targets[attrId] {
+		if containsFold(target.Name, req.Keyword) {
+			targets = append(targets, target)
+		}
+	}
+	writeJSON(w, map[string]interface{}{"Status": "OK", "Return": targets})
+}
+
+// matchKeyword reports whether the name or an attribute value of entity contains keyword,
+// ignoring case. An empty keyword matches every cientity.
+func matchKeyword(entity neatlogic.TbodyList, keyword string) bool {
+	if keyword == "" || containsFold(entity.Name, keyword) {
+		return true
+	}
+	for _, value := range entity.AttrEntityData {
+		entry, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, list := range []interface{}{entry["valueList"], entry["actualValueList"]} {
+			values, _ := list.([]interface{})
+			for _, v := range values {
+				if containsFold(fmt.Sprint(v), keyword) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// containsFold reports whether substr is within s, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// writeJSON writes body as a 200 JSON response.
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes a NeatLogic ERROR envelope with the given status code.
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{"Status": "ERROR", "Message": message})
+}
@@ -0,0 +1,425 @@
+package neatlogictest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+// fastRetries retries quickly, so that fault tests do not wait for backoff.
+var fastRetries = neatlogic.RetryPolicy{
+	MaxAttempts:     3,
+	InitialBackoff:  time.Millisecond,
+	RetryableStatus: []int{http.StatusServiceUnavailable},
+}
+
+// addHosts stores n cientities of CI 1 named host-1 to host-n.
+func addHosts(srv *neatlogictest.Server, n int) {
+	for i := 1; i <= n; i++ {
+		srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: fmt.Sprintf("host-%d", i)})
+	}
+}
+
+func TestNewClientLogsIn(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.JwtToken == "" {
+		t.Error("JwtToken is empty after login")
+	}
+	if got := srv.Requests(neatlogictest.PathLogin); got != 1 {
+		t.Errorf("logins = %d, want 1", got)
+	}
+}
+
+func TestNewClientRejectsWrongPassword(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+
+	_, err := srv.NewClient(neatlogic.WithCredentials(neatlogictest.DefaultUsername, "wrong"))
+	if err == nil {
+		t.Fatal("NewClient succeeded with a wrong password")
+	}
+}
+
+func TestSearchPaging(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	addHosts(srv, 45)
+	srv.AddCientity(neatlogic.TbodyList{CiId: 2, Name: "other"})
+	client, err := srv.NewClient(neatlogic.WithPrefetchWorkers(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keyword searches use the server's default page size of 20
+	entities, err := client.SearchCientityByKeyword(1, "host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entities) != 45 {
+		t.Fatalf("found %d entities, want 45", len(entities))
+	}
+	for i, entity := range entities {
+		if want := fmt.Sprintf("host-%d", i+1); entity.Name != want {
+			t.Errorf("entity %d = %s, want %s", i, entity.Name, want)
+		}
+	}
+	if got := srv.Requests(neatlogictest.PathCientitySearch); got != 3 {
+		t.Errorf("search requests = %d, want 3 pages", got)
+	}
+}
+
+func TestKeywordSearch(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	addHosts(srv, 3)
+	srv.AddCientity(neatlogic.TbodyList{
+		CiId: 1,
+		Name: "db-01",
+		AttrEntityData: map[string]interface{}{
+			"attr_1": map[string]interface{}{"valueList": []interface{}{"10.0.0.7"}},
+		},
+	})
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		keyword string
+		want    int
+	}{
+		{"HOST", 3},
+		{"host-2", 1},
+		{"10.0.0.7", 1},
+		{"missing", 0},
+	}
+	for _, tt := range tests {
+		entities, err := client.SearchCientityByKeyword(1, tt.keyword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entities) != tt.want {
+			t.Errorf("keyword %q found %d entities, want %d", tt.keyword, len(entities), tt.want)
+		}
+	}
+}
+
+func TestGetCientity(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	id := srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: "web-01"})
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entity, err := client.GetCientity(1, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entity.Name != "web-01" {
+		t.Errorf("name = %s, want web-01", entity.Name)
+	}
+	if _, err := client.GetCientity(1, id+1); !errors.Is(err, neatlogic.ErrNotFound) {
+		t.Errorf("unknown cientity: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFaultCount(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	addHosts(srv, 1)
+	client, err := srv.NewClient(neatlogic.WithRetryPolicy(fastRetries))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.InjectFault(neatlogictest.PathCientitySearch, neatlogictest.Fault{StatusCode: http.StatusServiceUnavailable, Count: 2})
+	entities, err := client.GetAllCientity(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entities) != 1 {
+		t.Errorf("found %d entities, want 1", len(entities))
+	}
+	if got := srv.Requests(neatlogictest.PathCientitySearch); got != 3 {
+		t.Errorf("search requests = %d, want 2 failures and 1 success", got)
+	}
+}
+
+func TestFaultUntilCleared(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	addHosts(srv, 1)
+	client, err := srv.NewClient(neatlogic.WithRetryPolicy(fastRetries))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.InjectFault(neatlogictest.PathCientitySearch, neatlogictest.Fault{StatusCode: http.StatusServiceUnavailable, Message: "maintenance"})
+	_, err = client.GetAllCientity(1)
+	var apiErr *neatlogic.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Message != "maintenance" {
+		t.Fatalf("err = %v, want 503 maintenance", err)
+	}
+	if got := srv.Requests(neatlogictest.PathCientitySearch); got != fastRetries.MaxAttempts {
+		t.Errorf("search requests = %d, want %d", got, fastRetries.MaxAttempts)
+	}
+
+	srv.ClearFaults()
+	if _, err := client.GetAllCientity(1); err != nil {
+		t.Errorf("after ClearFaults: %v", err)
+	}
+}
+
+func TestExpireTokensTriggersRefresh(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	addHosts(srv, 1)
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := client.JwtToken
+
+	srv.ExpireTokens()
+	if _, err := client.GetAllCientity(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Requests(neatlogictest.PathLogin); got != 2 {
+		t.Errorf("logins = %d, want 2", got)
+	}
+	if client.JwtToken == before {
+		t.Error("JwtToken was not replaced after the refresh")
+	}
+}
+
+func TestSearchTargetAttr(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	srv.SetTargetCis(5, []neatlogic.AReturn{{ID: 1, Name: "alice"}, {ID: 2, Name: "bob"}})
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := client.SearchTargetAttr(neatlogic.CRequestBody{Keyword: "ali"}, "5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || targets[0].Name != "alice" {
+		t.Errorf("targets = %v, want alice", targets)
+	}
+}
+
+func TestSaveCientity(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := client.SaveCientity(neatlogic.CientityPayload{
+		CiId:  1,
+		Attrs: []neatlogic.AttrValue{{AttrId: 1, ValueList: []interface{}{"web-01"}}, {AttrId: 2, ValueList: []interface{}{"10.0.0.1"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UpdateCientityAttrs(1, created.CiEntityId, []neatlogic.AttrValue{{AttrId: 2, ValueList: []interface{}{"10.0.0.2"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	entity, ok := srv.Cientity(created.CiEntityId)
+	if !ok {
+		t.Fatalf("cientity %d not stored", created.CiEntityId)
+	}
+	got := fmt.Sprint(entity.AttrEntityData["attr_1"], entity.AttrEntityData["attr_2"])
+	if want := "map[valueList:[web-01]] map[valueList:[10.0.0.2]]"; got != want {
+		t.Errorf("attributes = %s, want %s", got, want)
+	}
+	if _, err := client.UpdateCientityAttrs(1, created.CiEntityId+1, nil); err == nil {
+		t.Error("updating an unknown cientity: got no error")
+	}
+}
+
+func TestListCiAttrs(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	srv.SetCiAttrs(1, []neatlogic.CiAttr{{ID: 7, CiId: 1, Name: "root_password"}})
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", client.NeatlogicUri+neatlogictest.PathCiListAttr, strings.NewReader(`{"ciId":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := client.SendRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attrs neatlogic.CiAttrListResponse
+	if err := json.Unmarshal(body, &attrs); err != nil {
+		t.Fatal(err)
+	}
+	if len(attrs.CiAttrListReturn) != 1 || attrs.CiAttrListReturn[0].Name != "root_password" {
+		t.Errorf("attributes = %+v, want root_password", attrs.CiAttrListReturn)
+	}
+}
+
+func TestListCiRels(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	srv.SetCiRels(1, []neatlogic.CiRel{{ID: 5, FromCiId: 1, ToCiId: 2, ToName: "runs", Direction: neatlogic.RelFrom}})
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rels, err := client.ListCiRels(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rels) != 1 || rels[0].Name() != "runs" {
+		t.Errorf("relations = %+v, want runs", rels)
+	}
+	if rels, err := client.ListCiRels(2); err != nil || len(rels) != 0 {
+		t.Errorf("relations of CI 2 = %+v, %v, want none", rels, err)
+	}
+}
+
+func TestDeleteCientity(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	id := srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: "web-01"})
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A request with an unknown cientity deletes nothing
+	body := fmt.Sprintf(`{"ciEntityList":[{"id":%d,"ciId":1},{"id":%d,"ciId":1}]}`, id, id+1)
+	req, err := http.NewRequest("POST", client.NeatlogicUri+neatlogictest.PathCientityDelete, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SendRequest(req); err == nil {
+		t.Error("deleting an unknown cientity: got no error")
+	}
+	if _, ok := srv.Cientity(id); !ok {
+		t.Fatal("cientity deleted by a failed request")
+	}
+
+	if _, err := client.DeleteCientity(1, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.Cientity(id); ok {
+		t.Error("cientity still stored after delete")
+	}
+}
+
+func TestSearchCi(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	srv.AddCi(neatlogic.Ci{Name: "server", Label: "Server"})
+	srv.AddCi(neatlogic.Ci{Name: "rack", Label: "Server rack"})
+	srv.AddCi(neatlogic.Ci{Name: "app", Label: "Application"})
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", client.NeatlogicUri+neatlogictest.PathCiSearch, strings.NewReader(`{"keyword":"SERVER"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := client.SendRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cis neatlogic.CiListResponse
+	if err := json.Unmarshal(body, &cis); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, ci := range cis.CiListReturn.TbodyList {
+		names = append(names, ci.Name)
+	}
+	if strings.Join(names, " ") != "server rack" {
+		t.Errorf("found %v, want server and rack", names)
+	}
+}
+
+func TestBatchSaveCientity(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	existing := srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: "web-01"})
+	client, err := srv.NewClient(neatlogic.WithBatchPolicy(neatlogic.BatchPolicy{ChunkSize: 2, Workers: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostname := func(name string) []neatlogic.AttrValue {
+		return []neatlogic.AttrValue{{AttrId: 7, ValueList: []interface{}{name}}}
+	}
+	report, err := client.BatchSaveCientity(1, []neatlogic.CientityPayload{
+		{Attrs: hostname("web-02")},
+		{ID: existing, Attrs: hostname("web-01b")},
+		{Attrs: hostname("web-03")},
+		{ID: existing + 100, Attrs: hostname("missing")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []neatlogic.BatchItemStatus{neatlogic.BatchCreated, neatlogic.BatchUpdated, neatlogic.BatchCreated, neatlogic.BatchFailed}
+	for i, item := range report.Items {
+		if item.Status != want[i] {
+			t.Errorf("item %d: status = %s (%v), want %s", i, item.Status, item.Err, want[i])
+		}
+	}
+	// The first chunk is saved in one transaction group
+	first, second := report.Items[0].Result, report.Items[1].Result
+	if first.TransactionGroupId == 0 || first.TransactionGroupId != second.TransactionGroupId {
+		t.Errorf("transaction groups = %d, %d, want the same", first.TransactionGroupId, second.TransactionGroupId)
+	}
+	if first.TransactionId == second.TransactionId {
+		t.Errorf("both cientities recorded in transaction %d", first.TransactionId)
+	}
+	if second.CiEntityId != existing {
+		t.Errorf("updated cientity %d, want %d", second.CiEntityId, existing)
+	}
+	for _, item := range report.Items[:3] {
+		entity, ok := srv.Cientity(item.Result.CiEntityId)
+		if !ok {
+			t.Errorf("item %d: cientity %d not stored", item.Index, item.Result.CiEntityId)
+			continue
+		}
+		if _, ok := entity.AttrEntityData["attr_7"]; !ok {
+			t.Errorf("item %d: hostname not stored", item.Index)
+		}
+	}
+	// The rejected second chunk is saved one by one
+	if got := srv.Requests(neatlogictest.PathCientityBatch); got != 2 {
+		t.Errorf("batch save requests = %d, want 2", got)
+	}
+	if got := srv.Requests(neatlogictest.PathCientitySave); got != 2 {
+		t.Errorf("single save requests = %d, want 2", got)
+	}
+}
@@ -0,0 +1,241 @@
+package neatlogic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/hejingwen098/neatapi/auth"
+	"github.com/hejingwen098/neatapi/common"
+)
+
+// Option configures a NeatClient created by New.
+type Option func(*clientOptions)
+
+// clientOptions collects the settings applied by Option values.
+type clientOptions struct {
+	configPath    string
+	profile       string
+	baseURL       string
+	tenant        string
+	username      string
+	password      string
+	encrypt       string
+	httpClient    *http.Client
+	token         string
+	tokenCache    string
+	authenticator auth.Authenticator
+
+	retryPolicy     RetryPolicy
+	prefetchWorkers int
+	batchPolicy     BatchPolicy
+	middlewares     []Middleware
+	logger          *slog.Logger
+	sensitiveAttrs  []string
+}
+
+// WithConfigPath loads the configuration file at configPath.
+// NEATLOGIC_* environment variables take precedence over the file,
+// and settings given by other options take precedence over both.
+// Without WithConfigPath or WithProfile the environment is not read either.
+func WithConfigPath(configPath string) Option {
+	return func(o *clientOptions) {
+		o.configPath = configPath
+	}
+}
+
+// WithProfile selects a named profile from the configuration file.
+// If not given, the profile named by NEATLOGIC_PROFILE is used when a configuration file is.
+func WithProfile(profile string) Option {
+	return func(o *clientOptions) {
+		o.profile = profile
+	}
+}
+
+// WithBaseURL sets the base URL of the NeatLogic server, e.g. "http://127.0.0.1:8090".
+// The tenant is appended to it as the last path segment.
+func WithBaseURL(baseURL string) Option {
+	return func(o *clientOptions) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithTenant sets the NeatLogic tenant.
+func WithTenant(tenant string) Option {
+	return func(o *clientOptions) {
+		o.tenant = tenant
+	}
+}
+
+// WithCredentials sets the username and password used to log in.
+// It selects password authentication regardless of the configured auth type.
+func WithCredentials(username, password string) Option {
+	return func(o *clientOptions) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithEncrypt sets the password encryption method (base64 or md5) used to log in.
+func WithEncrypt(encrypt string) Option {
+	return func(o *clientOptions) {
+		o.encrypt = encrypt
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for login and API requests.
+// The TLS settings of the configuration file are not applied to a custom client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *clientOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithToken sets a pre-obtained JWT token, so New does not log in.
+// If credentials are configured as well, they are used to refresh the token once it expires.
+func WithToken(token string) Option {
+	return func(o *clientOptions) {
+		o.token = token
+	}
+}
+
+// WithTokenCache caches the login token in dir, so that processes logging in as the same user
+// share it until shortly before it expires instead of each logging in.
+// auth.DefaultTokenCacheDir returns a suitable per-user directory.
+func WithTokenCache(dir string) Option {
+	return func(o *clientOptions) {
+		o.tokenCache = dir
+	}
+}
+
+// WithAuthenticator sets the authenticator of every request, replacing the one
+// selected by the auth section of the configuration.
+func WithAuthenticator(authenticator auth.Authenticator) Option {
+	return func(o *clientOptions) {
+		o.authenticator = authenticator
+	}
+}
+
+// New creates a new NeatClient configured by opts.
+// Unlike NewNeatClient it reports configuration, network and authentication failures as errors.
+//
+// Parameters:
+//   - opts: Options configuring the client
+//
+// Returns:
+//   - *NeatClient: A new client instance ready to make API calls
+//   - error: An error if the configuration is invalid or authentication fails
+func New(opts ...Option) (*NeatClient, error) {
+	return NewCtx(context.Background(), opts...)
+}
+
+// NewCtx creates a new NeatClient configured by opts.
+// Secret references the selected authenticator needs are resolved (see common.ResolveSecretsCtx).
+// Their exec: commands and the login request are bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of secret resolution and the login request
+//   - opts: Options configuring the client
+//
+// Returns:
+//   - *NeatClient: A new client instance ready to make API calls
+//   - error: An error if the configuration is invalid or authentication fails
+func NewCtx(ctx context.Context, opts ...Option) (*NeatClient, error) {
+	o := &clientOptions{
+		prefetchWorkers: DefaultPrefetchWorkers,
+		batchPolicy:     DefaultBatchPolicy,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Load configuration file and environment, then let explicit options override them.
+	// A client built from options alone does not pick up credentials from the environment.
+	// Secrets given as options are literal, unlike secret references of the configuration.
+	var config common.Configs
+	var err error
+	if o.configPath != "" || o.profile != "" {
+		config, err = common.LoadProfile(o.configPath, o.profile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if o.tenant != "" {
+		config.Global.Neatlogic.Tenant = o.tenant
+	}
+	if o.username != "" {
+		config.Global.Auth.Type = auth.TypePassword
+		config.Global.Auth.Username = o.username
+		config.Global.Auth.Password = common.SecretPlain + o.password
+	}
+	if o.encrypt != "" {
+		config.Global.Auth.Encrypt = o.encrypt
+	}
+	if o.token != "" {
+		config.Global.Auth.Token = common.SecretPlain + o.token
+	}
+	if o.tokenCache != "" {
+		config.Global.Auth.TokenCache = o.tokenCache
+	}
+
+	// Build base URL
+	var neatlogicUri string
+	switch {
+	case o.baseURL != "":
+		neatlogicUri = strings.TrimRight(o.baseURL, "/")
+		if config.Global.Neatlogic.Tenant != "" {
+			neatlogicUri += "/" + config.Global.Neatlogic.Tenant
+		}
+	case config.Global.Neatlogic.Host != "":
+		neatlogicUri = config.Global.Neatlogic.Uri()
+	default:
+		return nil, errors.New("neatlogic: base URL is not configured")
+	}
+
+	client := &NeatClient{
+		Client:          o.httpClient,
+		NeatlogicUri:    neatlogicUri,
+		authenticator:   o.authenticator,
+		retryPolicy:     o.retryPolicy,
+		prefetchWorkers: o.prefetchWorkers,
+		batchPolicy:     o.batchPolicy,
+		middlewares:     o.middlewares,
+	}
+	if o.logger != nil {
+		client.logging = client.loggingMiddleware(o.logger, o.sensitiveAttrs)
+	}
+	if client.Client == nil {
+		client.Client, err = config.Global.Neatlogic.HTTPClient()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if client.authenticator != nil {
+		return client, nil
+	}
+
+	// Select the authenticator from the configuration, logging in unless a token was provided
+	if err := common.ResolveSecretsCtx(ctx, &config.Global.Auth); err != nil {
+		return nil, fmt.Errorf("neatlogic: %w", err)
+	}
+	authenticator, err := auth.NewAuthenticator(client.Client, client.NeatlogicUri, config.Global.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("neatlogic: %w", err)
+	}
+	switch authenticator := authenticator.(type) {
+	case *auth.PasswordAuth:
+		if authenticator.Token() == "" {
+			if err := authenticator.Login(ctx); err != nil {
+				return nil, err
+			}
+		}
+		client.JwtToken = authenticator.Token()
+	case auth.TokenAuth:
+		client.JwtToken = authenticator.Token
+	}
+	client.authenticator = authenticator
+	return client, nil
+}
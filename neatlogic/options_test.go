@@ -0,0 +1,106 @@
+package neatlogic_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hejingwen098/neatapi/common"
+	"github.com/hejingwen098/neatapi/neatlogic"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+// writeConfig writes a configuration file with the given auth section and returns its path.
+func writeConfig(t *testing.T, auth string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte("global:\n  auth:\n"+auth), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newClient creates a client of srv configured by the file at configPath.
+func newClient(srv *neatlogictest.Server, configPath string, opts ...neatlogic.Option) (*neatlogic.NeatClient, error) {
+	base := []neatlogic.Option{
+		neatlogic.WithConfigPath(configPath),
+		neatlogic.WithBaseURL(srv.URL),
+		neatlogic.WithTenant(srv.Tenant),
+		neatlogic.WithHTTPClient(srv.Client()),
+	}
+	return neatlogic.New(append(base, opts...)...)
+}
+
+func TestNewResolvesConfiguredPassword(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	secret := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(secret, []byte(neatlogictest.DefaultPassword+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := writeConfig(t, "    username: "+neatlogictest.DefaultUsername+"\n    password: file:"+secret+"\n")
+
+	client, err := newClient(srv, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.JwtToken == "" {
+		t.Error("JwtToken is empty after login")
+	}
+}
+
+func TestNewKeepsOptionSecretsLiteral(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	srv.AddUser("bob", "env:NEATAPI_TEST_UNSET")
+	config := writeConfig(t, "    username: admin\n    password: env:NEATAPI_TEST_UNSET\n")
+
+	if _, err := newClient(srv, config, neatlogic.WithCredentials("bob", "env:NEATAPI_TEST_UNSET")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewIgnoresUnusedSecrets(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	config := writeConfig(t, "    type: token\n    token: plain:env:token\n    password: env:NEATAPI_TEST_UNSET\n")
+
+	client, err := newClient(srv, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.JwtToken != "env:token" {
+		t.Errorf("JwtToken = %q, want env:token", client.JwtToken)
+	}
+}
+
+func TestNewIgnoresEnvironmentWithoutConfig(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	t.Setenv(common.EnvUsername, neatlogictest.DefaultUsername)
+	t.Setenv(common.EnvPassword, neatlogictest.DefaultPassword)
+	t.Setenv(common.EnvTenant, "other")
+	logged, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logins := srv.Requests(neatlogictest.PathLogin)
+
+	client, err := neatlogic.New(
+		neatlogic.WithBaseURL(srv.URL),
+		neatlogic.WithTenant(srv.Tenant),
+		neatlogic.WithHTTPClient(srv.Client()),
+		neatlogic.WithToken(logged.JwtToken),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireTokens()
+	if _, err := client.GetAllCientity(1); !errors.Is(err, neatlogic.ErrUnauthorized) {
+		t.Fatalf("err = %v, want ErrUnauthorized", err)
+	}
+	if got := srv.Requests(neatlogictest.PathLogin); got != logins {
+		t.Errorf("logins = %d, want %d", got, logins)
+	}
+}
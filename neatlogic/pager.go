@@ -0,0 +1,196 @@
+package neatlogic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// CientityPager iterates over the results of a paginated cientity search.
+// Pages are fetched lazily, one request at a time, so only the current page is held in memory.
+//
+// A pager is used like bufio.Scanner:
+//
+//	pager := client.GetAllCientityPager(ctx, ciId)
+//	for pager.Next() {
+//		entity := pager.Cientity()
+//		// ...
+//	}
+//	if err := pager.Err(); err != nil {
+//		// ...
+//	}
+type CientityPager struct {
+	ctx    context.Context
+	client *NeatClient
+	// body returns the search request body for the given page number.
+	body func(page int) interface{}
+
+	page     int
+	lastPage bool
+	rowNum   int
+	items    []TbodyList
+	index    int
+	current  TbodyList
+	err      error
+}
+
+// GetAllCientityPager returns a pager over all CMDB entities for a given configuration item ID.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of every page request
+//   - ciId: The configuration item ID to search for
+//
+// Returns:
+//   - *CientityPager: A pager that fetches the entities page by page
+func (c *NeatClient) GetAllCientityPager(ctx context.Context, ciId int64) *CientityPager {
+	return c.newCientityPager(ctx, allCientityBody(ciId))
+}
+
+// SearchCientityByFilterPager returns a pager over the CMDB entities matching the filter criteria.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of every page request
+//   - reqbody: The request body containing filter criteria
+//
+// Returns:
+//   - *CientityPager: A pager that fetches the entities page by page
+func (c *NeatClient) SearchCientityByFilterPager(ctx context.Context, reqbody CRequestBody) *CientityPager {
+	return c.newCientityPager(ctx, filterBody(reqbody))
+}
+
+// SearchCientityByKeywordPager returns a pager over the CMDB entities matching a keyword.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of every page request
+//   - ciId: The configuration item ID to search within
+//   - keyword: The keyword to search for
+//
+// Returns:
+//   - *CientityPager: A pager that fetches the entities page by page
+func (c *NeatClient) SearchCientityByKeywordPager(ctx context.Context, ciId int64, keyword string) *CientityPager {
+	return c.newCientityPager(ctx, keywordBody(ciId, keyword))
+}
+
+// allCientityBody builds the search request bodies used by GetAllCientity.
+func allCientityBody(ciId int64) func(page int) interface{} {
+	return func(page int) interface{} {
+		return CRequest{
+			CiId:        ciId,
+			CurrentPage: page,
+			PageSize:    100,
+		}
+	}
+}
+
+// filterBody builds the search request bodies used by SearchCientityByFilter.
+func filterBody(reqbody CRequestBody) func(page int) interface{} {
+	return func(page int) interface{} {
+		// Copy so that pages can be requested concurrently
+		body := reqbody
+		body.CurrentPage = page
+		return body
+	}
+}
+
+// keywordBody builds the search request bodies used by SearchCientityByKeyword.
+func keywordBody(ciId int64, keyword string) func(page int) interface{} {
+	return func(page int) interface{} {
+		return CRequest{
+			CiId:        ciId,
+			CurrentPage: page,
+			Keyword:     keyword,
+		}
+	}
+}
+
+// newCientityPager creates a pager that builds the request body of each page with body.
+func (c *NeatClient) newCientityPager(ctx context.Context, body func(page int) interface{}) *CientityPager {
+	return &CientityPager{
+		ctx:    ctx,
+		client: c,
+		body:   body,
+	}
+}
+
+// Next advances the pager to the next entity, fetching the next page when needed.
+// It returns false when there are no more entities, an error occurred or the context is done.
+func (p *CientityPager) Next() bool {
+	for p.index >= len(p.items) {
+		if p.err != nil || p.lastPage {
+			return false
+		}
+		if err := p.ctx.Err(); err != nil {
+			p.err = err
+			return false
+		}
+		result, err := p.client.searchPage(p.ctx, p.page+1, p.body(p.page+1))
+		if err != nil {
+			p.err = err
+			return false
+		}
+		p.page++
+		p.items = result.TbodyList
+		p.index = 0
+		p.rowNum = result.RowNum
+		p.lastPage = p.page >= result.PageCount
+	}
+	p.current = p.items[p.index]
+	p.index++
+	return true
+}
+
+// Cientity returns the entity the pager is positioned on by the last call to Next.
+func (p *CientityPager) Cientity() TbodyList {
+	return p.current
+}
+
+// Err returns the first error encountered by the pager, if any.
+func (p *CientityPager) Err() error {
+	return p.err
+}
+
+// Page returns the number of the last page fetched.
+func (p *CientityPager) Page() int {
+	return p.page
+}
+
+// RowNum returns the total number of matching entities as reported by the last page fetched.
+func (p *CientityPager) RowNum() int {
+	return p.rowNum
+}
+
+// collect drains the pager into a slice.
+func (p *CientityPager) collect() ([]TbodyList, error) {
+	var allCientity []TbodyList
+	for p.Next() {
+		allCientity = append(allCientity, p.Cientity())
+	}
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	return allCientity, nil
+}
+
+// searchPage fetches page number page of the cientity search API, requested by reqbody.
+func (c *NeatClient) searchPage(ctx context.Context, page int, reqbody interface{}) (CReturn, error) {
+	url := fmt.Sprintf("%s/api/rest/cmdb/cientity/search", c.NeatlogicUri)
+	jsonData, err := json.Marshal(reqbody)
+	if err != nil {
+		return CReturn{}, err
+	}
+	req, err := http.NewRequestWithContext(withPage(ctx, page), "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return CReturn{}, err
+	}
+	resp, err := c.SendRequest(req)
+	if err != nil {
+		return CReturn{}, err
+	}
+	var respBody CResponse
+	if err := json.Unmarshal(resp, &respBody); err != nil {
+		return CReturn{}, err
+	}
+	return respBody.CReturn, nil
+}
@@ -0,0 +1,162 @@
+package neatlogic_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+func TestPagerIteratesAllPages(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	addSearchHosts(srv)
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pager := client.SearchCientityByKeywordPager(context.Background(), 1, "host")
+	if pager.Page() != 0 || pager.RowNum() != 0 {
+		t.Errorf("before Next: page %d, rows %d, want 0, 0", pager.Page(), pager.RowNum())
+	}
+	var entities []neatlogic.TbodyList
+	for pager.Next() {
+		entities = append(entities, pager.Cientity())
+		if want := (len(entities)-1)/20 + 1; pager.Page() != want {
+			t.Fatalf("entity %d: page %d, want %d", len(entities), pager.Page(), want)
+		}
+		if pager.RowNum() != searchPages*20 {
+			t.Fatalf("entity %d: rows %d, want %d", len(entities), pager.RowNum(), searchPages*20)
+		}
+	}
+	if err := pager.Err(); err != nil {
+		t.Fatal(err)
+	}
+	checkHosts(t, entities)
+	if pager.Next() {
+		t.Error("Next after the last entity returned true")
+	}
+	if got := srv.Requests(neatlogictest.PathCientitySearch); got != searchPages {
+		t.Errorf("search requests = %d, want %d", got, searchPages)
+	}
+}
+
+func TestPagerStopsEarly(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	addSearchHosts(srv)
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pager := client.SearchCientityByKeywordPager(context.Background(), 1, "host")
+	for i := 1; i <= 5; i++ {
+		if !pager.Next() {
+			t.Fatalf("Next %d returned false: %v", i, pager.Err())
+		}
+		if want := fmt.Sprintf("host-%d", i); pager.Cientity().Name != want {
+			t.Errorf("entity %d = %s, want %s", i, pager.Cientity().Name, want)
+		}
+	}
+	if pager.Page() != 1 || pager.RowNum() != searchPages*20 {
+		t.Errorf("page %d, rows %d, want 1, %d", pager.Page(), pager.RowNum(), searchPages*20)
+	}
+	// Only the page being read has been fetched
+	if got := srv.Requests(neatlogictest.PathCientitySearch); got != 1 {
+		t.Errorf("search requests = %d, want 1", got)
+	}
+}
+
+func TestPagerEmpty(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pager := client.GetAllCientityPager(context.Background(), 1)
+	if pager.Next() {
+		t.Fatal("Next returned true without entities")
+	}
+	if err := pager.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if pager.Page() != 1 || pager.RowNum() != 0 {
+		t.Errorf("page %d, rows %d, want 1, 0", pager.Page(), pager.RowNum())
+	}
+}
+
+func TestPagerErrAfterFailingPage(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	addSearchHosts(srv)
+	client, err := srv.NewClient(neatlogic.WithBeforeRequest(func(req *http.Request) error {
+		// The third page fails
+		if strings.HasSuffix(req.URL.Path, neatlogictest.PathCientitySearch) && srv.Requests(neatlogictest.PathCientitySearch) == 2 {
+			srv.InjectFault(neatlogictest.PathCientitySearch, neatlogictest.Fault{StatusCode: http.StatusForbidden, Count: 1})
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pager := client.SearchCientityByKeywordPager(context.Background(), 1, "host")
+	n := 0
+	for pager.Next() {
+		n++
+	}
+	if !errors.Is(pager.Err(), neatlogic.ErrForbidden) {
+		t.Fatalf("Err = %v, want ErrForbidden", pager.Err())
+	}
+	if n != 40 {
+		t.Errorf("read %d entities before the error, want 40", n)
+	}
+	if pager.Page() != 2 {
+		t.Errorf("page = %d, want 2, the last page fetched", pager.Page())
+	}
+	// The pager stays failed instead of retrying the page
+	if pager.Next() {
+		t.Error("Next after an error returned true")
+	}
+	if got := srv.Requests(neatlogictest.PathCientitySearch); got != 3 {
+		t.Errorf("search requests = %d, want 3", got)
+	}
+}
+
+func TestPagerStopsWhenContextDone(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	addSearchHosts(srv)
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pager := client.SearchCientityByKeywordPager(ctx, 1, "host")
+	n := 0
+	for pager.Next() {
+		if n++; n == 20 {
+			cancel()
+		}
+	}
+	if !errors.Is(pager.Err(), context.Canceled) {
+		t.Fatalf("Err = %v, want context.Canceled", pager.Err())
+	}
+	if n != 20 {
+		t.Errorf("read %d entities, want the 20 of the first page", n)
+	}
+	if got := srv.Requests(neatlogictest.PathCientitySearch); got != 1 {
+		t.Errorf("search requests = %d, want 1", got)
+	}
+}
@@ -0,0 +1,90 @@
+package neatlogic
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultPrefetchWorkers is the number of pages fetched concurrently by clients created with New.
+const DefaultPrefetchWorkers = 4
+
+// WithPrefetchWorkers sets how many pages GetAllCientity, SearchCientityByFilter and
+// SearchCientityByKeyword fetch concurrently once the first page revealed the page count.
+// A value of 1 fetches pages one after another.
+func WithPrefetchWorkers(workers int) Option {
+	return func(o *clientOptions) {
+		o.prefetchWorkers = workers
+	}
+}
+
+// fetchAllPages fetches every page of a cientity search and returns the entities in page order.
+// After the first page, the remaining pages are fetched by up to prefetchWorkers concurrent
+// requests. The first failing page cancels all outstanding requests.
+func (c *NeatClient) fetchAllPages(ctx context.Context, body func(page int) interface{}) ([]TbodyList, error) {
+	if c.prefetchWorkers <= 1 {
+		return c.newCientityPager(ctx, body).collect()
+	}
+
+	first, err := c.searchPage(ctx, 1, body(1))
+	if err != nil {
+		return nil, err
+	}
+	if first.PageCount <= 1 {
+		return first.TbodyList, nil
+	}
+
+	pages := make([][]TbodyList, first.PageCount)
+	pages[0] = first.TbodyList
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	next := make(chan int)
+	for i := 0; i < min(c.prefetchWorkers, first.PageCount-1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range next {
+				result, err := c.searchPage(ctx, page, body(page))
+				if err != nil {
+					// Any page error cancels the rest
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				pages[page-1] = result.TbodyList
+			}
+		}()
+	}
+
+feed:
+	for page := 2; page <= first.PageCount; page++ {
+		select {
+		case next <- page:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Concatenate pages in order
+	var allCientity []TbodyList
+	for _, page := range pages {
+		allCientity = append(allCientity, page...)
+	}
+	return allCientity, nil
+}
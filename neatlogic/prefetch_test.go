@@ -0,0 +1,188 @@
+package neatlogic_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+// searchPages is the number of pages of a keyword search for "host" over the hosts stored by addSearchHosts,
+// at the fake server's default page size of 20.
+const searchPages = 8
+
+// addSearchHosts stores searchPages pages of cientities of CI 1 named host-1 upwards.
+func addSearchHosts(srv *neatlogictest.Server) {
+	for i := 1; i <= searchPages*20; i++ {
+		srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: fmt.Sprintf("host-%d", i)})
+	}
+}
+
+// pageTracker is a middleware recording the pages requested and the peak number of
+// concurrent requests. Earlier pages are delayed longer, so that concurrent pages complete
+// out of order.
+type pageTracker struct {
+	mu       sync.Mutex
+	pages    []int
+	inFlight int
+	peak     int
+}
+
+func (p *pageTracker) middleware(next neatlogic.Doer) neatlogic.Doer {
+	return neatlogic.DoerFunc(func(req *http.Request) (*http.Response, error) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		var search struct {
+			CurrentPage int `json:"currentPage"`
+		}
+		json.Unmarshal(body, &search)
+
+		p.mu.Lock()
+		p.pages = append(p.pages, search.CurrentPage)
+		p.inFlight++
+		p.peak = max(p.peak, p.inFlight)
+		p.mu.Unlock()
+		defer func() {
+			p.mu.Lock()
+			p.inFlight--
+			p.mu.Unlock()
+		}()
+
+		if search.CurrentPage > 1 {
+			time.Sleep(time.Duration(searchPages-search.CurrentPage) * 5 * time.Millisecond)
+		}
+		return next.Do(req)
+	})
+}
+
+// checkHosts checks that entities are all hosts stored by addSearchHosts, in order.
+func checkHosts(t *testing.T, entities []neatlogic.TbodyList) {
+	t.Helper()
+	if len(entities) != searchPages*20 {
+		t.Fatalf("found %d entities, want %d", len(entities), searchPages*20)
+	}
+	for i, entity := range entities {
+		if want := fmt.Sprintf("host-%d", i+1); entity.Name != want {
+			t.Fatalf("entity %d = %s, want %s", i, entity.Name, want)
+		}
+	}
+}
+
+func TestPrefetchKeepsPageOrder(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	addSearchHosts(srv)
+	var tracker pageTracker
+	client, err := srv.NewClient(neatlogic.WithMiddleware(tracker.middleware))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entities, err := client.SearchCientityByKeyword(1, "host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHosts(t, entities)
+	if got := srv.Requests(neatlogictest.PathCientitySearch); got != searchPages {
+		t.Errorf("search requests = %d, want %d", got, searchPages)
+	}
+	if tracker.peak > neatlogic.DefaultPrefetchWorkers {
+		t.Errorf("peak concurrent requests = %d, want at most %d", tracker.peak, neatlogic.DefaultPrefetchWorkers)
+	}
+}
+
+func TestPrefetchCancelsAfterPageError(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	addSearchHosts(srv)
+	client, err := srv.NewClient(neatlogic.WithBeforeRequest(func(req *http.Request) error {
+		// Every page after the first fails
+		if strings.HasSuffix(req.URL.Path, neatlogictest.PathCientitySearch) && srv.Requests(neatlogictest.PathCientitySearch) > 0 {
+			srv.InjectFault(neatlogictest.PathCientitySearch, neatlogictest.Fault{StatusCode: http.StatusForbidden})
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entities, err := client.SearchCientityByKeyword(1, "host")
+	if !errors.Is(err, neatlogic.ErrForbidden) {
+		t.Fatalf("err = %v, want ErrForbidden", err)
+	}
+	if entities != nil {
+		t.Errorf("entities = %d, want none", len(entities))
+	}
+	// Each worker sends at most one page before the first error cancels the rest
+	if got := srv.Requests(neatlogictest.PathCientitySearch); got > 1+neatlogic.DefaultPrefetchWorkers {
+		t.Errorf("search requests = %d, want at most %d", got, 1+neatlogic.DefaultPrefetchWorkers)
+	}
+}
+
+func TestPrefetchSequentialFallback(t *testing.T) {
+	for _, workers := range []int{1, 0, -1} {
+		t.Run(fmt.Sprint(workers), func(t *testing.T) {
+			srv := neatlogictest.NewServer()
+			defer srv.Close()
+			addSearchHosts(srv)
+			var tracker pageTracker
+			client, err := srv.NewClient(
+				neatlogic.WithPrefetchWorkers(workers),
+				neatlogic.WithMiddleware(tracker.middleware),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entities, err := client.SearchCientityByKeyword(1, "host")
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkHosts(t, entities)
+			if tracker.peak != 1 {
+				t.Errorf("peak concurrent requests = %d, want 1", tracker.peak)
+			}
+			if want := []int{1, 2, 3, 4, 5, 6, 7, 8}; fmt.Sprint(tracker.pages) != fmt.Sprint(want) {
+				t.Errorf("pages = %v, want %v", tracker.pages, want)
+			}
+		})
+	}
+}
+
+func TestPrefetchSequentialStopsAtPageError(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	addSearchHosts(srv)
+	client, err := srv.NewClient(
+		neatlogic.WithPrefetchWorkers(1),
+		neatlogic.WithBeforeRequest(func(req *http.Request) error {
+			// The fourth page fails
+			if strings.HasSuffix(req.URL.Path, neatlogictest.PathCientitySearch) && srv.Requests(neatlogictest.PathCientitySearch) == 3 {
+				srv.InjectFault(neatlogictest.PathCientitySearch, neatlogictest.Fault{StatusCode: http.StatusForbidden, Count: 1})
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.SearchCientityByKeyword(1, "host"); !errors.Is(err, neatlogic.ErrForbidden) {
+		t.Fatalf("err = %v, want ErrForbidden", err)
+	}
+	if got := srv.Requests(neatlogictest.PathCientitySearch); got != 4 {
+		t.Errorf("search requests = %d, want 4", got)
+	}
+}
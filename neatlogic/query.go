@@ -0,0 +1,300 @@
+package neatlogic
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Expression is a comparison operator of a cientity search filter.
+type Expression string
+
+// Expressions understood by the cientity search API.
+const (
+	// ExprEqual matches values equal to one of the given values.
+	ExprEqual Expression = "equal"
+	// ExprNotEqual matches values different from all given values.
+	ExprNotEqual Expression = "notequal"
+	// ExprLike matches values containing the given value.
+	ExprLike Expression = "like"
+	// ExprNotLike matches values not containing the given value.
+	ExprNotLike Expression = "notlike"
+	// ExprIsNull matches empty values. It takes no values.
+	ExprIsNull Expression = "is-null"
+	// ExprIsNotNull matches non-empty values. It takes no values.
+	ExprIsNotNull Expression = "is-not-null"
+	// ExprGreaterThan matches values greater than the given value.
+	ExprGreaterThan Expression = "greater-than"
+	// ExprLessThan matches values less than the given value.
+	ExprLessThan Expression = "less-than"
+	// ExprBetween matches values between the two given values.
+	ExprBetween Expression = "between"
+)
+
+// Keys of the filter maps in CRequestBody.AttrFilterList and CRequestBody.RelFilterList.
+//
+// An attribute filter looks like
+//
+//	{"attrId": 123, "expression": "equal", "valueList": ["10.0.0.1"]}
+//
+// and a relation filter like
+//
+//	{"relId": 456, "direction": "from", "expression": "like", "valueList": ["pay"]}
+//
+// where direction is the end of the relation the searched CI is on.
+const (
+	FilterKeyAttrId     = "attrId"
+	FilterKeyRelId      = "relId"
+	FilterKeyDirection  = "direction"
+	FilterKeyExpression = "expression"
+	FilterKeyValueList  = "valueList"
+)
+
+// QueryBuilder builds a CRequestBody for SearchCientityByFilter, resolving attribute and
+// relation names to IDs through the CI model.
+//
+//	ci, err := client.GetCiByName("server")
+//	// ...
+//	body, err := neatlogic.Query(ci).Attr("ip").Equal("10.0.0.1").Rel("app").Like("pay").Build()
+//	// ...
+//	entities, err := client.SearchCientityByFilter(body)
+type QueryBuilder struct {
+	ci             Ci
+	keyword        string
+	pageSize       int
+	attrFilterList []map[string]interface{}
+	relFilterList  []map[string]interface{}
+	errs           []error
+}
+
+// Condition is a filter on a single attribute or relation, completed by choosing an expression.
+type Condition struct {
+	query *QueryBuilder
+	// filter holds the ID (and direction) keys of the filter; nil if the name did not resolve.
+	filter map[string]interface{}
+	isRel  bool
+}
+
+// Query starts a query over the cientities of ci.
+// The CI must have been retrieved with its attributes and relations, e.g. by GetCi or GetCiByName.
+func Query(ci Ci) *QueryBuilder {
+	return &QueryBuilder{ci: ci}
+}
+
+// Keyword restricts the search to cientities matching keyword.
+func (q *QueryBuilder) Keyword(keyword string) *QueryBuilder {
+	q.keyword = keyword
+	return q
+}
+
+// PageSize sets the number of cientities per page requested from NeatLogic.
+func (q *QueryBuilder) PageSize(pageSize int) *QueryBuilder {
+	q.pageSize = pageSize
+	return q
+}
+
+// Attr starts a filter on the attribute with the given name.
+func (q *QueryBuilder) Attr(name string) *Condition {
+	attr, ok := q.ci.Attr(name)
+	if !ok {
+		q.errs = append(q.errs, fmt.Errorf("ci %s has no attribute %q", q.ci.Name, name))
+		return &Condition{query: q}
+	}
+	return &Condition{
+		query:  q,
+		filter: map[string]interface{}{FilterKeyAttrId: attr.ID},
+	}
+}
+
+// Rel starts a filter on the relation with the given name as seen from the CI.
+// Values are matched against the names of the related cientities.
+func (q *QueryBuilder) Rel(name string) *Condition {
+	rel, ok := q.ci.Rel(name)
+	if !ok {
+		q.errs = append(q.errs, fmt.Errorf("ci %s has no relation %q", q.ci.Name, name))
+		return &Condition{query: q, isRel: true}
+	}
+	direction := rel.Direction
+	if direction == "" {
+		direction = RelFrom
+	}
+	return &Condition{
+		query: q,
+		filter: map[string]interface{}{
+			FilterKeyRelId:     rel.ID,
+			FilterKeyDirection: direction,
+		},
+		isRel: true,
+	}
+}
+
+// Build compiles the query into a request body for SearchCientityByFilter.
+//
+// Returns:
+//   - CRequestBody: The request body
+//   - error: An error if an attribute or relation name did not resolve or an expression got the wrong number of values
+func (q *QueryBuilder) Build() (CRequestBody, error) {
+	if err := errors.Join(q.errs...); err != nil {
+		return CRequestBody{}, err
+	}
+	return CRequestBody{
+		CiId:           int(q.ci.ID),
+		PageSize:       q.pageSize,
+		Keyword:        q.keyword,
+		AttrFilterList: q.attrFilterList,
+		RelFilterList:  q.relFilterList,
+	}, nil
+}
+
+// Expr completes the condition with an arbitrary expression and values.
+func (c *Condition) Expr(expression Expression, values ...interface{}) *QueryBuilder {
+	if c.filter == nil {
+		// The name did not resolve; the error is reported by Build
+		return c.query
+	}
+	if err := checkValueCount(expression, len(values)); err != nil {
+		c.query.errs = append(c.query.errs, err)
+		return c.query
+	}
+	filter := make(map[string]interface{}, len(c.filter)+2)
+	for key, value := range c.filter {
+		filter[key] = value
+	}
+	if values == nil {
+		values = []interface{}{}
+	}
+	filter[FilterKeyExpression] = expression
+	filter[FilterKeyValueList] = values
+	if c.isRel {
+		c.query.relFilterList = append(c.query.relFilterList, filter)
+	} else {
+		c.query.attrFilterList = append(c.query.attrFilterList, filter)
+	}
+	return c.query
+}
+
+// Equal matches values equal to one of values.
+func (c *Condition) Equal(values ...interface{}) *QueryBuilder {
+	return c.Expr(ExprEqual, values...)
+}
+
+// NotEqual matches values different from all values.
+func (c *Condition) NotEqual(values ...interface{}) *QueryBuilder {
+	return c.Expr(ExprNotEqual, values...)
+}
+
+// Like matches values containing value.
+func (c *Condition) Like(value interface{}) *QueryBuilder {
+	return c.Expr(ExprLike, value)
+}
+
+// NotLike matches values not containing value.
+func (c *Condition) NotLike(value interface{}) *QueryBuilder {
+	return c.Expr(ExprNotLike, value)
+}
+
+// IsNull matches empty values.
+func (c *Condition) IsNull() *QueryBuilder {
+	return c.Expr(ExprIsNull)
+}
+
+// IsNotNull matches non-empty values.
+func (c *Condition) IsNotNull() *QueryBuilder {
+	return c.Expr(ExprIsNotNull)
+}
+
+// GreaterThan matches values greater than value.
+func (c *Condition) GreaterThan(value interface{}) *QueryBuilder {
+	return c.Expr(ExprGreaterThan, value)
+}
+
+// LessThan matches values less than value.
+func (c *Condition) LessThan(value interface{}) *QueryBuilder {
+	return c.Expr(ExprLessThan, value)
+}
+
+// Between matches values between from and to.
+func (c *Condition) Between(from, to interface{}) *QueryBuilder {
+	return c.Expr(ExprBetween, from, to)
+}
+
+// TypedCondition is a Condition of a typed query Q, such as the queries generated by neatapi gen.
+// Completing it returns the typed query instead of the QueryBuilder, so chained calls keep their type.
+type TypedCondition[Q any] struct {
+	condition *Condition
+	query     Q
+}
+
+// NewTypedCondition wraps condition, started on the QueryBuilder of query.
+func NewTypedCondition[Q any](condition *Condition, query Q) TypedCondition[Q] {
+	return TypedCondition[Q]{condition: condition, query: query}
+}
+
+// Expr completes the condition with an arbitrary expression and values.
+func (c TypedCondition[Q]) Expr(expression Expression, values ...interface{}) Q {
+	c.condition.Expr(expression, values...)
+	return c.query
+}
+
+// Equal matches values equal to one of values.
+func (c TypedCondition[Q]) Equal(values ...interface{}) Q {
+	return c.Expr(ExprEqual, values...)
+}
+
+// NotEqual matches values different from all values.
+func (c TypedCondition[Q]) NotEqual(values ...interface{}) Q {
+	return c.Expr(ExprNotEqual, values...)
+}
+
+// Like matches values containing value.
+func (c TypedCondition[Q]) Like(value interface{}) Q {
+	return c.Expr(ExprLike, value)
+}
+
+// NotLike matches values not containing value.
+func (c TypedCondition[Q]) NotLike(value interface{}) Q {
+	return c.Expr(ExprNotLike, value)
+}
+
+// IsNull matches empty values.
+func (c TypedCondition[Q]) IsNull() Q {
+	return c.Expr(ExprIsNull)
+}
+
+// IsNotNull matches non-empty values.
+func (c TypedCondition[Q]) IsNotNull() Q {
+	return c.Expr(ExprIsNotNull)
+}
+
+// GreaterThan matches values greater than value.
+func (c TypedCondition[Q]) GreaterThan(value interface{}) Q {
+	return c.Expr(ExprGreaterThan, value)
+}
+
+// LessThan matches values less than value.
+func (c TypedCondition[Q]) LessThan(value interface{}) Q {
+	return c.Expr(ExprLessThan, value)
+}
+
+// Between matches values between from and to.
+func (c TypedCondition[Q]) Between(from, to interface{}) Q {
+	return c.Expr(ExprBetween, from, to)
+}
+
+// checkValueCount reports an error if expression does not accept count values.
+func checkValueCount(expression Expression, count int) error {
+	switch expression {
+	case ExprIsNull, ExprIsNotNull:
+		if count != 0 {
+			return fmt.Errorf("expression %s takes no values", expression)
+		}
+	case ExprBetween:
+		if count != 2 {
+			return fmt.Errorf("expression %s takes two values", expression)
+		}
+	default:
+		if count == 0 {
+			return fmt.Errorf("expression %s takes at least one value", expression)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package neatlogic_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+// serverCi is a CI with two attributes and relations on either end.
+var serverCi = neatlogic.Ci{
+	ID:   1,
+	Name: "server",
+	Attrs: []neatlogic.CiAttr{
+		{ID: 10, CiId: 1, Name: "ip"},
+		{ID: 11, CiId: 1, Name: "cpu"},
+	},
+	Rels: []neatlogic.CiRel{
+		{ID: 5, FromCiId: 1, ToCiId: 2, ToName: "app", Direction: neatlogic.RelFrom},
+		{ID: 6, FromCiId: 3, ToCiId: 1, FromName: "rack", Direction: neatlogic.RelTo},
+		{ID: 7, FromCiId: 1, ToCiId: 4, ToName: "legacy"},
+	},
+}
+
+// serverQuery is a typed query over serverCi, like the queries generated by neatapi gen.
+type serverQuery struct {
+	*neatlogic.QueryBuilder
+}
+
+func (q serverQuery) IP() neatlogic.TypedCondition[serverQuery] {
+	return neatlogic.NewTypedCondition(q.Attr("ip"), q)
+}
+
+func (q serverQuery) Rack() neatlogic.TypedCondition[serverQuery] {
+	return neatlogic.NewTypedCondition(q.Rel("rack"), q)
+}
+
+// jsonValue returns v as decoded from its JSON encoding, for comparing request bodies.
+func jsonValue(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+// checkJSON checks that got encodes to the same JSON as the document want.
+func checkJSON(t *testing.T, got interface{}, want string) {
+	t.Helper()
+	var wantValue interface{}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatal(err)
+	}
+	if gotValue := jsonValue(t, got); !reflect.DeepEqual(gotValue, wantValue) {
+		data, _ := json.Marshal(got)
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+}
+
+func TestQueryBuild(t *testing.T) {
+	body, err := neatlogic.Query(serverCi).
+		Keyword("web").
+		PageSize(50).
+		Attr("ip").Equal("10.0.0.1", "10.0.0.2").
+		Attr("cpu").Between(2, 8).
+		Attr("ip").IsNotNull().
+		Rel("app").Like("pay").
+		Rel("rack").NotEqual("r1").
+		Rel("legacy").IsNull().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkJSON(t, body.AttrFilterList, `[
+		{"attrId": 10, "expression": "equal", "valueList": ["10.0.0.1", "10.0.0.2"]},
+		{"attrId": 11, "expression": "between", "valueList": [2, 8]},
+		{"attrId": 10, "expression": "is-not-null", "valueList": []}
+	]`)
+	checkJSON(t, body.RelFilterList, `[
+		{"relId": 5, "direction": "from", "expression": "like", "valueList": ["pay"]},
+		{"relId": 6, "direction": "to", "expression": "notequal", "valueList": ["r1"]},
+		{"relId": 7, "direction": "from", "expression": "is-null", "valueList": []}
+	]`)
+	if body.CiId != 1 || body.Keyword != "web" || body.PageSize != 50 {
+		t.Errorf("ciId %d, keyword %q, page size %d, want 1, web, 50", body.CiId, body.Keyword, body.PageSize)
+	}
+}
+
+func TestQueryBuildErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *neatlogic.QueryBuilder
+		want  []string
+	}{
+		{"unknown attribute", neatlogic.Query(serverCi).Attr("mac").Equal("x"), []string{`no attribute "mac"`}},
+		{"unknown relation", neatlogic.Query(serverCi).Rel("owner").Equal("x"), []string{`no relation "owner"`}},
+		{"equal without values", neatlogic.Query(serverCi).Attr("ip").Equal(), []string{"equal takes at least one value"}},
+		{"like without values", neatlogic.Query(serverCi).Rel("app").Expr(neatlogic.ExprLike), []string{"like takes at least one value"}},
+		{"is-null with a value", neatlogic.Query(serverCi).Attr("ip").Expr(neatlogic.ExprIsNull, "x"), []string{"is-null takes no values"}},
+		{"is-not-null with a value", neatlogic.Query(serverCi).Attr("ip").Expr(neatlogic.ExprIsNotNull, "x"), []string{"is-not-null takes no values"}},
+		{"between with one value", neatlogic.Query(serverCi).Attr("cpu").Expr(neatlogic.ExprBetween, 1), []string{"between takes two values"}},
+		{"between with three values", neatlogic.Query(serverCi).Attr("cpu").Expr(neatlogic.ExprBetween, 1, 2, 3), []string{"between takes two values"}},
+		{
+			"every error",
+			neatlogic.Query(serverCi).Attr("mac").Equal("x").Rel("owner").Like("y").Attr("ip").Equal(),
+			[]string{`no attribute "mac"`, `no relation "owner"`, "equal takes at least one value"},
+		},
+	}
+	for _, tt := range tests {
+		body, err := tt.query.Build()
+		if err == nil {
+			t.Errorf("%s: got no error", tt.name)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q does not mention %q", tt.name, err, want)
+			}
+		}
+		if !reflect.DeepEqual(body, neatlogic.CRequestBody{}) {
+			t.Errorf("%s: body = %+v, want zero", tt.name, body)
+		}
+	}
+}
+
+func TestTypedCondition(t *testing.T) {
+	body, err := serverQuery{neatlogic.Query(serverCi)}.
+		IP().Like("10.0.").
+		Rack().Equal("r1", "r2").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkJSON(t, body.AttrFilterList, `[{"attrId": 10, "expression": "like", "valueList": ["10.0."]}]`)
+	checkJSON(t, body.RelFilterList, `[{"relId": 6, "direction": "to", "expression": "equal", "valueList": ["r1", "r2"]}]`)
+
+	if _, err := (serverQuery{neatlogic.Query(serverCi)}).IP().Expr(neatlogic.ExprBetween, 1).Build(); err == nil {
+		t.Error("between with one value: got no error")
+	}
+}
+
+func TestQuerySearchBody(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	var bodies bodyRecorder
+	client, err := srv.NewClient(neatlogic.WithMiddleware(bodies.middleware))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, err := neatlogic.Query(serverCi).Attr("ip").Equal("10.0.0.1").Rel("app").Like("pay").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SearchCientityByFilter(query); err != nil {
+		t.Fatal(err)
+	}
+	sent := bodies.only(t, neatlogictest.PathCientitySearch)
+	checkJSON(t, sent["attrFilterList"], `[{"attrId": 10, "expression": "equal", "valueList": ["10.0.0.1"]}]`)
+	checkJSON(t, sent["relFilterList"], `[{"relId": 5, "direction": "from", "expression": "like", "valueList": ["pay"]}]`)
+	if sent["ciId"] != float64(1) || sent["currentPage"] != float64(1) {
+		t.Errorf("ciId %v, currentPage %v, want 1, 1", sent["ciId"], sent["currentPage"])
+	}
+}
@@ -0,0 +1,128 @@
+package neatlogic
+
+import (
+	"context"
+)
+
+// Actions applied to relation values by a partial cientity save.
+const (
+	relActionInsert = "insert"
+	relActionDelete = "delete"
+)
+
+// ListCiRels retrieves the relation definitions of a configuration item.
+//
+// Parameters:
+//   - ciId: The configuration item ID
+//
+// Returns:
+//   - []CiRel: The relations the configuration item is on either end of
+//   - error: An error if the operation fails
+func (c *NeatClient) ListCiRels(ciId int64) ([]CiRel, error) {
+	return c.ListCiRelsCtx(context.Background(), ciId)
+}
+
+// ListCiRelsCtx retrieves the relation definitions of a configuration item.
+// The request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the request
+//   - ciId: The configuration item ID
+//
+// Returns:
+//   - []CiRel: The relations the configuration item is on either end of
+//   - error: An error if the operation fails
+func (c *NeatClient) ListCiRelsCtx(ctx context.Context, ciId int64) ([]CiRel, error) {
+	var respBody CiRelListResponse
+	if err := c.postJSON(ctx, "/api/rest/cmdb/ci/listrel", ciIdRequest{CiId: ciId}, &respBody); err != nil {
+		return nil, err
+	}
+	return respBody.CiRelListReturn, nil
+}
+
+// AddRelEntity relates a cientity to another one. Other relations of the cientity are kept.
+// The change is committed immediately.
+//
+// Parameters:
+//   - ciId: The configuration item ID of the cientity
+//   - ciEntityId: The ID of the cientity
+//   - rel: The relation, its direction as seen from the cientity, and the related cientity
+//
+// Returns:
+//   - SaveResult: The transaction recording the change
+//   - error: An error if the relation direction is invalid or the operation fails
+func (c *NeatClient) AddRelEntity(ciId int64, ciEntityId int64, rel RelValue) (SaveResult, error) {
+	return c.AddRelEntityCtx(context.Background(), ciId, ciEntityId, rel)
+}
+
+// AddRelEntityCtx relates a cientity to another one. Other relations of the cientity are kept.
+// The request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the request
+//   - ciId: The configuration item ID of the cientity
+//   - ciEntityId: The ID of the cientity
+//   - rel: The relation, its direction as seen from the cientity, and the related cientity
+//
+// Returns:
+//   - SaveResult: The transaction recording the change
+//   - error: An error if the relation direction is invalid or the operation fails
+func (c *NeatClient) AddRelEntityCtx(ctx context.Context, ciId int64, ciEntityId int64, rel RelValue) (SaveResult, error) {
+	return c.saveRelEntity(ctx, ciId, ciEntityId, rel, relActionInsert)
+}
+
+// DeleteRelEntity removes the relation between a cientity and another one.
+// Other relations of the cientity are kept. The change is committed immediately.
+//
+// Parameters:
+//   - ciId: The configuration item ID of the cientity
+//   - ciEntityId: The ID of the cientity
+//   - rel: The relation, its direction as seen from the cientity, and the related cientity
+//
+// Returns:
+//   - SaveResult: The transaction recording the change
+//   - error: An error if the relation direction is invalid or the operation fails
+func (c *NeatClient) DeleteRelEntity(ciId int64, ciEntityId int64, rel RelValue) (SaveResult, error) {
+	return c.DeleteRelEntityCtx(context.Background(), ciId, ciEntityId, rel)
+}
+
+// DeleteRelEntityCtx removes the relation between a cientity and another one.
+// Other relations of the cientity are kept. The request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the request
+//   - ciId: The configuration item ID of the cientity
+//   - ciEntityId: The ID of the cientity
+//   - rel: The relation, its direction as seen from the cientity, and the related cientity
+//
+// Returns:
+//   - SaveResult: The transaction recording the change
+//   - error: An error if the relation direction is invalid or the operation fails
+func (c *NeatClient) DeleteRelEntityCtx(ctx context.Context, ciId int64, ciEntityId int64, rel RelValue) (SaveResult, error) {
+	return c.saveRelEntity(ctx, ciId, ciEntityId, rel, relActionDelete)
+}
+
+// saveRelEntity applies action to a single relation value through a partial cientity save.
+func (c *NeatClient) saveRelEntity(ctx context.Context, ciId int64, ciEntityId int64, rel RelValue, action string) (SaveResult, error) {
+	payload := CientityPayload{
+		CiId: ciId,
+		ID:   ciEntityId,
+		Rels: []RelValue{rel},
+	}
+	reqbody, err := payload.saveBody(editModePartial)
+	if err != nil {
+		return SaveResult{}, err
+	}
+	for key, group := range reqbody.RelEntityData {
+		for i := range group.ValueList {
+			group.ValueList[i].Action = action
+		}
+		reqbody.RelEntityData[key] = group
+	}
+
+	var respBody SaveResponse
+	if err := c.postJSON(ctx, "/api/rest/cmdb/cientity/save", reqbody, &respBody); err != nil {
+		return SaveResult{}, err
+	}
+	return respBody.SaveReturn, nil
+}
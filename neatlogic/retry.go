@@ -0,0 +1,164 @@
+package neatlogic
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how SendRequest retries transient failures, see RetryMiddleware.
+// Retries happen per request, so a paginated search that hits a transient
+// failure resumes from the failed page instead of starting over.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per request, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles with every further retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, including delays requested by Retry-After.
+	MaxBackoff time.Duration
+	// Jitter randomizes every delay by up to this fraction (0 to 1) in either direction.
+	Jitter float64
+	// RetryableStatus lists the HTTP status codes that are retried.
+	// Network errors are always retried. Writes, such as saving or deleting cientities,
+	// are only retried if the server cannot have processed them, see Idempotent.
+	RetryableStatus []int
+}
+
+// DefaultRetryPolicy retries network errors, 429 and 5xx gateway errors up to three times.
+// Writes are only retried if the server cannot have processed them.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+	Jitter:         0.2,
+	RetryableStatus: []int{
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// WithRetryPolicy sets the retry policy used by SendRequest. Retries are disabled by default.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *clientOptions) {
+		o.retryPolicy = policy
+	}
+}
+
+// Idempotent returns a copy of ctx marking the requests bound to it as safe to send more than once,
+// so that the retry policy retries them like reads. Only use it for writes the server deduplicates.
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey, true)
+}
+
+// readEndpoints are the last path segments of the NeatLogic APIs that only read data.
+var readEndpoints = []string{"search", "get", "listattr", "listrel"}
+
+// idempotent reports whether req may be sent again after an attempt the server may have processed:
+// reads, requests with an idempotent method and requests marked with Idempotent.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if marked, _ := req.Context().Value(idempotentKey).(bool); marked {
+		return true
+	}
+	return slices.Contains(readEndpoints, path.Base(req.URL.Path))
+}
+
+// retryable reports whether the outcome of an attempt of req is a transient failure worth another attempt.
+// Writes are only retried if the attempt cannot have been processed: the connection failed,
+// or the server refused it with 429, or with 503 and Retry-After.
+func (p RetryPolicy) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		if !idempotent(req) {
+			var opErr *net.OpError
+			return errors.As(err, &opErr) && opErr.Op == "dial"
+		}
+		// Network errors surface as *url.Error from http.Client.Do
+		var urlErr *url.Error
+		return errors.As(err, &urlErr)
+	}
+	if !slices.Contains(p.RetryableStatus, resp.StatusCode) {
+		return false
+	}
+	if idempotent(req) {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		return resp.Header.Get("Retry-After") != ""
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry (1 for the first retry).
+// A delay requested by the server through Retry-After takes precedence.
+func (p RetryPolicy) backoff(retry int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return p.capBackoff(retryAfter)
+	}
+	delay := p.InitialBackoff
+	for i := 1; i < retry; i++ {
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff || delay > math.MaxInt64/2 {
+			// Capped, or doubling would overflow
+			break
+		}
+		delay *= 2
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+	return p.capBackoff(delay)
+}
+
+// capBackoff limits delay to MaxBackoff if one is set.
+func (p RetryPolicy) capBackoff(delay time.Duration) time.Duration {
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return delay
+}
+
+// sleepCtx waits for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
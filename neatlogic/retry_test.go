@@ -0,0 +1,100 @@
+package neatlogic
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		want   []time.Duration
+	}{
+		{
+			name:   "uncapped",
+			policy: RetryPolicy{InitialBackoff: 100 * time.Millisecond},
+			want:   []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond},
+		},
+		{
+			name:   "capped",
+			policy: RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond},
+			want:   []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := tt.policy.backoff(i+1, 0); got != want {
+					t.Errorf("backoff(%d) = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoffRetryAfter(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second}
+	if got := policy.backoff(1, 2*time.Second); got != 2*time.Second {
+		t.Errorf("backoff with Retry-After 2s = %v, want 2s", got)
+	}
+	if got := policy.backoff(1, time.Minute); got != 5*time.Second {
+		t.Errorf("backoff with Retry-After 1m = %v, want cap 5s", got)
+	}
+}
+
+func TestRetryPolicyBackoffOverflow(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second}
+	if got := policy.backoff(100, 0); got <= 0 {
+		t.Errorf("backoff(100) = %v, want positive", got)
+	}
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	dialErr := &url.Error{Op: "Post", URL: "http://neatlogic", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	readErr := &url.Error{Op: "Post", URL: "http://neatlogic", Err: &net.OpError{Op: "read", Err: errors.New("connection reset")}}
+	response := func(statusCode int, retryAfter string) *http.Response {
+		resp := &http.Response{StatusCode: statusCode, Header: http.Header{}}
+		if retryAfter != "" {
+			resp.Header.Set("Retry-After", retryAfter)
+		}
+		return resp
+	}
+	tests := []struct {
+		name string
+		path string
+		ctx  context.Context
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{"read 500", "/demo/api/rest/cmdb/cientity/search", context.Background(), response(500, ""), nil, true},
+		{"read network error", "/demo/api/rest/cmdb/cientity/get", context.Background(), nil, readErr, true},
+		{"read 400", "/demo/api/rest/cmdb/cientity/search", context.Background(), response(400, ""), nil, false},
+		{"write 500", "/demo/api/rest/cmdb/cientity/save", context.Background(), response(500, ""), nil, false},
+		{"write 504", "/demo/api/rest/cmdb/cientity/batchsave", context.Background(), response(504, ""), nil, false},
+		{"write 503", "/demo/api/rest/cmdb/transaction/commit", context.Background(), response(503, ""), nil, false},
+		{"write 503 with Retry-After", "/demo/api/rest/cmdb/transaction/commit", context.Background(), response(503, "1"), nil, true},
+		{"write 429", "/demo/api/rest/cmdb/cientity/delete", context.Background(), response(429, ""), nil, true},
+		{"write read error", "/demo/api/rest/cmdb/cientity/save", context.Background(), nil, readErr, false},
+		{"write dial error", "/demo/api/rest/cmdb/cientity/save", context.Background(), nil, dialErr, true},
+		{"idempotent write 500", "/demo/api/rest/cmdb/cientity/save", Idempotent(context.Background()), response(500, ""), nil, true},
+		{"canceled", "/demo/api/rest/cmdb/cientity/search", context.Background(), nil, &url.Error{Op: "Post", Err: context.Canceled}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(tt.ctx, http.MethodPost, "http://neatlogic"+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := DefaultRetryPolicy.retryable(req, tt.resp, tt.err); got != tt.want {
+				t.Errorf("retryable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package neatlogic
+
+import (
+	"context"
+	"fmt"
+)
+
+// RelDirection is the direction of a relation as seen from a cientity.
+type RelDirection string
+
+const (
+	// RelFrom means the cientity is the source of the relation.
+	RelFrom RelDirection = "from"
+	// RelTo means the cientity is the target of the relation.
+	RelTo RelDirection = "to"
+)
+
+// Edit modes of the cientity save API.
+const (
+	// editModeGlobal replaces all attributes and relations of the cientity.
+	editModeGlobal = "global"
+	// editModePartial only changes the attributes and relations present in the request.
+	editModePartial = "partial"
+)
+
+// AttrValue represents the values of a single attribute of a cientity.
+type AttrValue struct {
+	// AttrId is the attribute ID.
+	AttrId int64
+	// ValueList contains the attribute values. Select and reference attributes take IDs.
+	ValueList []interface{}
+}
+
+// RelValue represents a relation from or to another cientity.
+type RelValue struct {
+	// RelId is the relation ID.
+	RelId int64
+	// Direction is the direction of the relation as seen from the saved cientity.
+	// It must be RelFrom or RelTo.
+	Direction RelDirection
+	// CiId is the configuration item ID of the related cientity.
+	CiId int64
+	// CiEntityId is the ID of the related cientity.
+	CiEntityId int64
+}
+
+// CientityPayload represents a cientity to create or update.
+type CientityPayload struct {
+	// CiId is the configuration item ID of the cientity.
+	CiId int64
+	// ID is the cientity ID. Leave it zero to create a new cientity.
+	ID int64
+	// UUID is an optional client-side unique identifier of the cientity.
+	UUID string
+	// Attrs contains the attribute values of the cientity.
+	Attrs []AttrValue
+	// Rels contains the relations of the cientity.
+	Rels []RelValue
+	// Description is recorded on the transaction.
+	Description string
+	// DeferCommit leaves the transaction uncommitted instead of applying it immediately.
+	DeferCommit bool
+}
+
+// SaveResult represents the result of a cientity write operation.
+type SaveResult struct {
+	// CiEntityId is the ID of the created or updated cientity.
+	CiEntityId int64 `json:"ciEntityId"`
+	// TransactionId is the ID of the transaction recording the change.
+	TransactionId int64 `json:"transactionId"`
+	// TransactionGroupId is the ID of the transaction group recording the change.
+	TransactionGroupId int64 `json:"transactionGroupId"`
+}
+
+// SaveResponse represents the response structure for cientity write operations.
+type SaveResponse struct {
+	// Status indicates the operation status (OK or ERROR).
+	Status string `json:"Status"`
+	// SaveReturn contains the result of the write operation.
+	SaveReturn SaveResult `json:"Return"`
+	// TimeCost is the time cost of the operation in milliseconds.
+	TimeCost int64 `json:"TimeCost"`
+}
+
+// saveCientityBody is the request body of the cientity save API.
+type saveCientityBody struct {
+	ID             int64                          `json:"id,omitempty"`
+	UUID           string                         `json:"uuid,omitempty"`
+	CiId           int64                          `json:"ciId"`
+	EditMode       string                         `json:"editMode"`
+	NeedCommit     bool                           `json:"needCommit"`
+	Description    string                         `json:"description,omitempty"`
+	AttrEntityData map[string]attrEntityValue     `json:"attrEntityData"`
+	RelEntityData  map[string]relEntityValueGroup `json:"relEntityData"`
+}
+
+// attrEntityValue is the value of an attribute in the cientity save API.
+type attrEntityValue struct {
+	ValueList []interface{} `json:"valueList"`
+}
+
+// relEntityValueGroup holds the related cientities of one relation direction in the cientity save API.
+type relEntityValueGroup struct {
+	ValueList []relEntityValue `json:"valueList"`
+}
+
+// relEntityValue references a related cientity in the cientity save API.
+type relEntityValue struct {
+	CiId       int64 `json:"ciId"`
+	CiEntityId int64 `json:"ciEntityId"`
+	// Action is set by AddRelEntity and DeleteRelEntity only; other saves leave it out.
+	Action string `json:"action,omitempty"`
+}
+
+// deleteCientityBody is the request body of the cientity delete API.
+type deleteCientityBody struct {
+	CiEntityList []deleteCientityItem `json:"ciEntityList"`
+	NeedCommit   bool                 `json:"needCommit"`
+	Description  string               `json:"description,omitempty"`
+}
+
+// deleteCientityItem identifies a cientity in the cientity delete API.
+type deleteCientityItem struct {
+	ID   int64 `json:"id"`
+	CiId int64 `json:"ciId"`
+}
+
+// attrKey returns the key of an attribute in attrEntityData.
+func attrKey(attrId int64) string {
+	return fmt.Sprintf("attr_%d", attrId)
+}
+
+// relKey returns the key of a relation direction in relEntityData.
+func relKey(relId int64, direction RelDirection) string {
+	return fmt.Sprintf("rel%s_%d", direction, relId)
+}
+
+// saveBody builds the save API request body for the payload.
+// It returns an error if a relation has a direction other than RelFrom or RelTo.
+func (p CientityPayload) saveBody(editMode string) (saveCientityBody, error) {
+	body := saveCientityBody{
+		ID:             p.ID,
+		UUID:           p.UUID,
+		CiId:           p.CiId,
+		EditMode:       editMode,
+		NeedCommit:     !p.DeferCommit,
+		Description:    p.Description,
+		AttrEntityData: map[string]attrEntityValue{},
+		RelEntityData:  map[string]relEntityValueGroup{},
+	}
+	for _, attr := range p.Attrs {
+		valueList := attr.ValueList
+		if valueList == nil {
+			// An empty list clears the attribute
+			valueList = []interface{}{}
+		}
+		body.AttrEntityData[attrKey(attr.AttrId)] = attrEntityValue{ValueList: valueList}
+	}
+	for _, rel := range p.Rels {
+		if rel.Direction != RelFrom && rel.Direction != RelTo {
+			return saveCientityBody{}, fmt.Errorf("relation %d: direction %q is neither %s nor %s", rel.RelId, rel.Direction, RelFrom, RelTo)
+		}
+		key := relKey(rel.RelId, rel.Direction)
+		group := body.RelEntityData[key]
+		group.ValueList = append(group.ValueList, relEntityValue{
+			CiId:       rel.CiId,
+			CiEntityId: rel.CiEntityId,
+		})
+		body.RelEntityData[key] = group
+	}
+	return body, nil
+}
+
+// SaveCientity creates a cientity, or replaces all attributes and relations of an existing one.
+//
+// Parameters:
+//   - payload: The cientity to save; a zero ID creates a new cientity
+//
+// Returns:
+//   - SaveResult: The ID of the saved cientity and the transaction recording the change
+//   - error: An error if a relation direction is invalid or the operation fails
+func (c *NeatClient) SaveCientity(payload CientityPayload) (SaveResult, error) {
+	return c.SaveCientityCtx(context.Background(), payload)
+}
+
+// SaveCientityCtx creates a cientity, or replaces all attributes and relations of an existing one.
+// The request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the request
+//   - payload: The cientity to save; a zero ID creates a new cientity
+//
+// Returns:
+//   - SaveResult: The ID of the saved cientity and the transaction recording the change
+//   - error: An error if a relation direction is invalid or the operation fails
+func (c *NeatClient) SaveCientityCtx(ctx context.Context, payload CientityPayload) (SaveResult, error) {
+	reqbody, err := payload.saveBody(editModeGlobal)
+	if err != nil {
+		return SaveResult{}, err
+	}
+	var respBody SaveResponse
+	if err := c.postJSON(ctx, "/api/rest/cmdb/cientity/save", reqbody, &respBody); err != nil {
+		return SaveResult{}, err
+	}
+	return respBody.SaveReturn, nil
+}
+
+// UpdateCientityAttrs changes the given attributes of an existing cientity and leaves all
+// other attributes and relations untouched. The change is committed immediately.
+//
+// Parameters:
+//   - ciId: The configuration item ID
+//   - ciEntityId: The ID of the cientity to update
+//   - attrs: The attribute values to set
+//
+// Returns:
+//   - SaveResult: The ID of the updated cientity and the transaction recording the change
+//   - error: An error if the operation fails
+func (c *NeatClient) UpdateCientityAttrs(ciId int64, ciEntityId int64, attrs []AttrValue) (SaveResult, error) {
+	return c.UpdateCientityAttrsCtx(context.Background(), ciId, ciEntityId, attrs)
+}
+
+// UpdateCientityAttrsCtx changes the given attributes of an existing cientity and leaves all
+// other attributes and relations untouched. The request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the request
+//   - ciId: The configuration item ID
+//   - ciEntityId: The ID of the cientity to update
+//   - attrs: The attribute values to set
+//
+// Returns:
+//   - SaveResult: The ID of the updated cientity and the transaction recording the change
+//   - error: An error if the operation fails
+func (c *NeatClient) UpdateCientityAttrsCtx(ctx context.Context, ciId int64, ciEntityId int64, attrs []AttrValue) (SaveResult, error) {
+	payload := CientityPayload{
+		CiId:  ciId,
+		ID:    ciEntityId,
+		Attrs: attrs,
+	}
+	reqbody, err := payload.saveBody(editModePartial)
+	if err != nil {
+		return SaveResult{}, err
+	}
+	var respBody SaveResponse
+	if err := c.postJSON(ctx, "/api/rest/cmdb/cientity/save", reqbody, &respBody); err != nil {
+		return SaveResult{}, err
+	}
+	return respBody.SaveReturn, nil
+}
+
+// DeleteCientity deletes a cientity. The deletion is committed immediately.
+//
+// Parameters:
+//   - ciId: The configuration item ID
+//   - ciEntityId: The ID of the cientity to delete
+//
+// Returns:
+//   - SaveResult: The transaction recording the deletion
+//   - error: An error if the operation fails
+func (c *NeatClient) DeleteCientity(ciId int64, ciEntityId int64) (SaveResult, error) {
+	return c.DeleteCientityCtx(context.Background(), ciId, ciEntityId)
+}
+
+// DeleteCientityCtx deletes a cientity. The request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the request
+//   - ciId: The configuration item ID
+//   - ciEntityId: The ID of the cientity to delete
+//
+// Returns:
+//   - SaveResult: The transaction recording the deletion
+//   - error: An error if the operation fails
+func (c *NeatClient) DeleteCientityCtx(ctx context.Context, ciId int64, ciEntityId int64) (SaveResult, error) {
+	reqbody := deleteCientityBody{
+		CiEntityList: []deleteCientityItem{{ID: ciEntityId, CiId: ciId}},
+		NeedCommit:   true,
+	}
+	var respBody SaveResponse
+	if err := c.postJSON(ctx, "/api/rest/cmdb/cientity/delete", reqbody, &respBody); err != nil {
+		return SaveResult{}, err
+	}
+	return respBody.SaveReturn, nil
+}
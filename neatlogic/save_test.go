@@ -0,0 +1,245 @@
+package neatlogic_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+	"github.com/hejingwen098/neatapi/neatlogic/neatlogictest"
+)
+
+// newRecordedClient creates a client of srv recording the bodies of its requests.
+func newRecordedClient(t *testing.T, srv *neatlogictest.Server) (*neatlogic.NeatClient, *bodyRecorder) {
+	t.Helper()
+	var bodies bodyRecorder
+	client, err := srv.NewClient(neatlogic.WithMiddleware(bodies.middleware))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, &bodies
+}
+
+func TestSaveCientityRelBody(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	client, bodies := newRecordedClient(t, srv)
+
+	_, err := client.SaveCientity(neatlogic.CientityPayload{
+		CiId: 1,
+		Rels: []neatlogic.RelValue{
+			{RelId: 5, Direction: neatlogic.RelFrom, CiId: 2, CiEntityId: 20},
+			{RelId: 5, Direction: neatlogic.RelFrom, CiId: 2, CiEntityId: 21},
+			{RelId: 6, Direction: neatlogic.RelTo, CiId: 3, CiEntityId: 30},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkJSON(t, bodies.only(t, neatlogictest.PathCientitySave)["relEntityData"], `{
+		"relfrom_5": {"valueList": [{"ciId": 2, "ciEntityId": 20}, {"ciId": 2, "ciEntityId": 21}]},
+		"relto_6": {"valueList": [{"ciId": 3, "ciEntityId": 30}]}
+	}`)
+}
+
+func TestRelEntityBody(t *testing.T) {
+	tests := []struct {
+		name string
+		save func(*neatlogic.NeatClient, int64, int64, neatlogic.RelValue) (neatlogic.SaveResult, error)
+		want string
+	}{
+		{"add", (*neatlogic.NeatClient).AddRelEntity, "insert"},
+		{"delete", (*neatlogic.NeatClient).DeleteRelEntity, "delete"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := neatlogictest.NewServer()
+			defer srv.Close()
+			id := srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: "web-01"})
+			client, bodies := newRecordedClient(t, srv)
+
+			result, err := tt.save(client, 1, id, neatlogic.RelValue{RelId: 6, Direction: neatlogic.RelTo, CiId: 3, CiEntityId: 30})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.CiEntityId != id {
+				t.Errorf("saved cientity %d, want %d", result.CiEntityId, id)
+			}
+			checkJSON(t, bodies.only(t, neatlogictest.PathCientitySave), `{
+				"id": `+fmt.Sprint(id)+`,
+				"ciId": 1,
+				"editMode": "partial",
+				"needCommit": true,
+				"attrEntityData": {},
+				"relEntityData": {"relto_6": {"valueList": [{"ciId": 3, "ciEntityId": 30, "action": "`+tt.want+`"}]}}
+			}`)
+		})
+	}
+}
+
+func TestSaveRejectsInvalidDirection(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	id := srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: "web-01"})
+	client, err := srv.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, direction := range []neatlogic.RelDirection{"", "both", "From"} {
+		rel := neatlogic.RelValue{RelId: 5, Direction: direction, CiId: 2, CiEntityId: 20}
+		if _, err := client.SaveCientity(neatlogic.CientityPayload{CiId: 1, Rels: []neatlogic.RelValue{rel}}); err == nil {
+			t.Errorf("SaveCientity with direction %q: got no error", direction)
+		}
+		if _, err := client.AddRelEntity(1, id, rel); err == nil {
+			t.Errorf("AddRelEntity with direction %q: got no error", direction)
+		}
+		if _, err := client.DeleteRelEntity(1, id, rel); err == nil {
+			t.Errorf("DeleteRelEntity with direction %q: got no error", direction)
+		}
+	}
+	if got := srv.Requests(neatlogictest.PathCientitySave); got != 0 {
+		t.Errorf("save requests = %d, want 0", got)
+	}
+}
+
+func TestSaveCientityBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload neatlogic.CientityPayload
+		want    string
+	}{
+		{
+			name: "create",
+			payload: neatlogic.CientityPayload{
+				CiId:        1,
+				UUID:        "b5e4",
+				Description: "import",
+				Attrs: []neatlogic.AttrValue{
+					{AttrId: 7, ValueList: []interface{}{"web-01"}},
+					{AttrId: 8},
+				},
+				Rels: []neatlogic.RelValue{{RelId: 5, Direction: neatlogic.RelFrom, CiId: 2, CiEntityId: 20}},
+			},
+			want: `{
+				"uuid": "b5e4",
+				"ciId": 1,
+				"editMode": "global",
+				"needCommit": true,
+				"description": "import",
+				"attrEntityData": {"attr_7": {"valueList": ["web-01"]}, "attr_8": {"valueList": []}},
+				"relEntityData": {"relfrom_5": {"valueList": [{"ciId": 2, "ciEntityId": 20}]}}
+			}`,
+		},
+		{
+			name:    "deferred commit",
+			payload: neatlogic.CientityPayload{CiId: 1, DeferCommit: true},
+			want: `{
+				"ciId": 1,
+				"editMode": "global",
+				"needCommit": false,
+				"attrEntityData": {},
+				"relEntityData": {}
+			}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := neatlogictest.NewServer()
+			defer srv.Close()
+			client, bodies := newRecordedClient(t, srv)
+
+			result, err := client.SaveCientity(tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.CiEntityId == 0 || result.TransactionId == 0 {
+				t.Errorf("result = %+v, want cientity and transaction IDs", result)
+			}
+			checkJSON(t, bodies.only(t, neatlogictest.PathCientitySave), tt.want)
+		})
+	}
+}
+
+func TestSaveCientityReplacesAttrs(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	id := srv.AddCientity(neatlogic.TbodyList{CiId: 1, AttrEntityData: map[string]interface{}{
+		"attr_7": map[string]interface{}{"valueList": []interface{}{"web-01"}},
+		"attr_8": map[string]interface{}{"valueList": []interface{}{"10.0.0.1"}},
+	}})
+	client, bodies := newRecordedClient(t, srv)
+
+	payload := neatlogic.CientityPayload{CiId: 1, ID: id, Attrs: []neatlogic.AttrValue{{AttrId: 7, ValueList: []interface{}{"web-02"}}}}
+	if _, err := client.SaveCientity(payload); err != nil {
+		t.Fatal(err)
+	}
+	sent := bodies.only(t, neatlogictest.PathCientitySave)
+	if sent["id"] != float64(id) || sent["editMode"] != "global" {
+		t.Errorf("id %v, editMode %v, want %d, global", sent["id"], sent["editMode"], id)
+	}
+	// A global save drops the attributes left out
+	entity, _ := srv.Cientity(id)
+	checkJSON(t, entity.AttrEntityData, `{"attr_7": {"valueList": ["web-02"]}}`)
+}
+
+func TestUpdateCientityAttrsBody(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	id := srv.AddCientity(neatlogic.TbodyList{CiId: 1, AttrEntityData: map[string]interface{}{
+		"attr_7": map[string]interface{}{"valueList": []interface{}{"web-01"}},
+		"attr_8": map[string]interface{}{"valueList": []interface{}{"10.0.0.1"}},
+		"attr_9": map[string]interface{}{"valueList": []interface{}{"prod"}},
+	}})
+	client, bodies := newRecordedClient(t, srv)
+
+	result, err := client.UpdateCientityAttrs(1, id, []neatlogic.AttrValue{
+		{AttrId: 7, ValueList: []interface{}{"web-02"}},
+		{AttrId: 8},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CiEntityId != id {
+		t.Errorf("updated cientity %d, want %d", result.CiEntityId, id)
+	}
+	checkJSON(t, bodies.only(t, neatlogictest.PathCientitySave), `{
+		"id": `+fmt.Sprint(id)+`,
+		"ciId": 1,
+		"editMode": "partial",
+		"needCommit": true,
+		"attrEntityData": {"attr_7": {"valueList": ["web-02"]}, "attr_8": {"valueList": []}},
+		"relEntityData": {}
+	}`)
+	// A partial save keeps the attributes left out and clears those given an empty list
+	entity, _ := srv.Cientity(id)
+	checkJSON(t, entity.AttrEntityData, `{
+		"attr_7": {"valueList": ["web-02"]},
+		"attr_8": {"valueList": []},
+		"attr_9": {"valueList": ["prod"]}
+	}`)
+}
+
+func TestDeleteCientityBody(t *testing.T) {
+	srv := neatlogictest.NewServer()
+	defer srv.Close()
+	id := srv.AddCientity(neatlogic.TbodyList{CiId: 1, Name: "web-01"})
+	client, bodies := newRecordedClient(t, srv)
+
+	result, err := client.DeleteCientity(1, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TransactionId == 0 {
+		t.Errorf("result = %+v, want a transaction ID", result)
+	}
+	checkJSON(t, bodies.only(t, neatlogictest.PathCientityDelete), `{
+		"ciEntityList": [{"id": `+fmt.Sprint(id)+`, "ciId": 1}],
+		"needCommit": true
+	}`)
+	if _, ok := srv.Cientity(id); ok {
+		t.Error("cientity still stored after delete")
+	}
+	if _, err := client.DeleteCientity(1, id); err == nil {
+		t.Error("deleting a deleted cientity: got no error")
+	}
+}
@@ -0,0 +1,330 @@
+package neatlogic
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TransactionStatus is the state of a CMDB transaction.
+type TransactionStatus string
+
+// Transaction states, spelled as NeatLogic reports them.
+const (
+	// TransactionCommitted means the change has been applied to the cientity.
+	TransactionCommitted TransactionStatus = "commited"
+	// TransactionUncommitted means the change is recorded but not applied yet.
+	TransactionUncommitted TransactionStatus = "uncommit"
+	// TransactionRecovered means the change has been rolled back.
+	TransactionRecovered TransactionStatus = "recover"
+	// TransactionExpired means the change was never committed and can no longer be.
+	TransactionExpired TransactionStatus = "expired"
+)
+
+// Transaction represents a recorded change of a cientity.
+// Times are in milliseconds since the Unix epoch; zero if the step did not happen.
+type Transaction struct {
+	// ID is the identifier of the transaction.
+	ID int64 `json:"id"`
+	// CiId is the configuration item ID of the changed cientity.
+	CiId int64 `json:"ciId"`
+	// CiEntityId is the ID of the changed cientity.
+	CiEntityId int64 `json:"ciEntityId"`
+	// CiEntityName is the name of the changed cientity.
+	CiEntityName string `json:"ciEntityName"`
+	// Action is the kind of change: insert, update or delete.
+	Action string `json:"action"`
+	// Status is the state of the transaction.
+	Status TransactionStatus `json:"status"`
+	// InputFrom is the channel the change came in through, e.g. page, import or itsm.
+	InputFrom string `json:"inputFrom"`
+	// Source describes where the change originated.
+	Source string `json:"source"`
+	// Description is the description given with the change.
+	Description string `json:"description"`
+	// CreateUser is the user who recorded the change.
+	CreateUser string `json:"createUser"`
+	// CreateTime is when the change was recorded.
+	CreateTime int64 `json:"createTime"`
+	// CommitUser is the user who committed the change.
+	CommitUser string `json:"commitUser"`
+	// CommitTime is when the change was committed.
+	CommitTime int64 `json:"commitTime"`
+	// RecoverUser is the user who rolled the change back.
+	RecoverUser string `json:"recoverUser"`
+	// RecoverTime is when the change was rolled back.
+	RecoverTime int64 `json:"recoverTime"`
+	// Error is the reason a commit failed, if it did.
+	Error string `json:"error"`
+}
+
+// TransactionFilter selects the transactions returned by ListTransactions.
+// Zero fields do not restrict the result.
+type TransactionFilter struct {
+	// CiId restricts the result to cientities of a configuration item.
+	CiId int64
+	// CiEntityId restricts the result to a single cientity.
+	CiEntityId int64
+	// Status restricts the result to transactions in a state.
+	Status TransactionStatus
+	// Start restricts the result to transactions recorded at or after a time.
+	Start time.Time
+	// End restricts the result to transactions recorded before a time.
+	End time.Time
+}
+
+// AttrChange represents the values of an attribute before and after a transaction.
+type AttrChange struct {
+	// AttrId is the identifier of the attribute.
+	AttrId int64
+	// Name is the name of the attribute.
+	Name string
+	// Label is the display name of the attribute.
+	Label string
+	// OldValueList contains the stored values before the change.
+	OldValueList []interface{}
+	// NewValueList contains the stored values after the change.
+	NewValueList []interface{}
+	// OldActualValueList contains the display values before the change, if they differ from the stored ones.
+	OldActualValueList []interface{}
+	// NewActualValueList contains the display values after the change, if they differ from the stored ones.
+	NewActualValueList []interface{}
+}
+
+// TransactionDetail represents a transaction together with the attribute values it changed.
+type TransactionDetail struct {
+	Transaction
+	// AttrChanges contains the changed attributes, ordered by attribute ID.
+	AttrChanges []AttrChange
+}
+
+// transactionSearchRequest is the request body of the transaction search API.
+type transactionSearchRequest struct {
+	CiId        int64             `json:"ciId,omitempty"`
+	CiEntityId  int64             `json:"ciEntityId,omitempty"`
+	Status      TransactionStatus `json:"status,omitempty"`
+	StartTime   int64             `json:"startTime,omitempty"`
+	EndTime     int64             `json:"endTime,omitempty"`
+	PageSize    int               `json:"pageSize"`
+	CurrentPage int               `json:"currentPage"`
+}
+
+// transactionIdRequest is the request body of APIs that take a transaction ID.
+type transactionIdRequest struct {
+	TransactionId int64 `json:"transactionId"`
+}
+
+// TransactionListReturn represents the return data of the transaction search API.
+type TransactionListReturn struct {
+	// PageCount is the total number of pages in the result set.
+	PageCount int `json:"pageCount"`
+	// TbodyList contains the transactions in the current page.
+	TbodyList []Transaction `json:"tbodyList"`
+}
+
+// TransactionListResponse represents the response structure for the transaction search API.
+type TransactionListResponse struct {
+	// Status indicates the operation status (OK or ERROR).
+	Status string `json:"Status"`
+	// TransactionListReturn contains the transactions found.
+	TransactionListReturn TransactionListReturn `json:"Return"`
+	// TimeCost is the time cost of the operation in milliseconds.
+	TimeCost int64 `json:"TimeCost"`
+}
+
+// TransactionReturn represents the return data of the transaction get API.
+type TransactionReturn struct {
+	Transaction
+	// CiEntityTransaction contains the recorded change of the cientity.
+	CiEntityTransaction struct {
+		// AttrEntityData contains the changed attributes, keyed like TbodyList.AttrEntityData.
+		// Besides valueList and actualValueList, entries carry oldValueList and oldActualValueList.
+		AttrEntityData map[string]interface{} `json:"attrEntityData"`
+	} `json:"ciEntityTransactionVo"`
+}
+
+// TransactionResponse represents the response structure for the transaction get API.
+type TransactionResponse struct {
+	// Status indicates the operation status (OK or ERROR).
+	Status string `json:"Status"`
+	// TransactionReturn contains the transaction.
+	TransactionReturn TransactionReturn `json:"Return"`
+	// TimeCost is the time cost of the operation in milliseconds.
+	TimeCost int64 `json:"TimeCost"`
+}
+
+// ListTransactions retrieves the transactions matching filter.
+//
+// Parameters:
+//   - filter: The configuration item, cientity, state and time range to list transactions for
+//
+// Returns:
+//   - []Transaction: A slice of all matching transactions
+//   - error: An error if the operation fails
+func (c *NeatClient) ListTransactions(filter TransactionFilter) ([]Transaction, error) {
+	return c.ListTransactionsCtx(context.Background(), filter)
+}
+
+// ListTransactionsCtx retrieves the transactions matching filter.
+// Every page request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the requests
+//   - filter: The configuration item, cientity, state and time range to list transactions for
+//
+// Returns:
+//   - []Transaction: A slice of all matching transactions
+//   - error: An error if the operation fails
+func (c *NeatClient) ListTransactionsCtx(ctx context.Context, filter TransactionFilter) ([]Transaction, error) {
+	reqbody := transactionSearchRequest{
+		CiId:       filter.CiId,
+		CiEntityId: filter.CiEntityId,
+		Status:     filter.Status,
+		PageSize:   100,
+	}
+	if !filter.Start.IsZero() {
+		reqbody.StartTime = filter.Start.UnixMilli()
+	}
+	if !filter.End.IsZero() {
+		reqbody.EndTime = filter.End.UnixMilli()
+	}
+
+	var allTransaction []Transaction
+	for currentPage := 1; ; currentPage++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		reqbody.CurrentPage = currentPage
+		var respBody TransactionListResponse
+		if err := c.postJSON(withPage(ctx, currentPage), "/api/rest/cmdb/transaction/search", reqbody, &respBody); err != nil {
+			return nil, err
+		}
+		allTransaction = append(allTransaction, respBody.TransactionListReturn.TbodyList...)
+		if currentPage >= respBody.TransactionListReturn.PageCount {
+			break
+		}
+	}
+	return allTransaction, nil
+}
+
+// GetTransaction retrieves a transaction with the old and new values of the attributes it changed.
+//
+// Parameters:
+//   - transactionId: The transaction ID, e.g. SaveResult.TransactionId
+//
+// Returns:
+//   - TransactionDetail: The transaction and its attribute changes
+//   - error: An error if the operation fails
+func (c *NeatClient) GetTransaction(transactionId int64) (TransactionDetail, error) {
+	return c.GetTransactionCtx(context.Background(), transactionId)
+}
+
+// GetTransactionCtx retrieves a transaction with the old and new values of the attributes it changed.
+// The request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the request
+//   - transactionId: The transaction ID, e.g. SaveResult.TransactionId
+//
+// Returns:
+//   - TransactionDetail: The transaction and its attribute changes
+//   - error: An error if the operation fails
+func (c *NeatClient) GetTransactionCtx(ctx context.Context, transactionId int64) (TransactionDetail, error) {
+	var respBody TransactionResponse
+	if err := c.postJSON(ctx, "/api/rest/cmdb/transaction/get", transactionIdRequest{TransactionId: transactionId}, &respBody); err != nil {
+		return TransactionDetail{}, err
+	}
+	ret := respBody.TransactionReturn
+	return TransactionDetail{
+		Transaction: ret.Transaction,
+		AttrChanges: attrChanges(ret.CiEntityTransaction.AttrEntityData),
+	}, nil
+}
+
+// CommitTransaction applies an uncommitted transaction to its cientity.
+//
+// Parameters:
+//   - transactionId: The transaction ID
+//
+// Returns:
+//   - error: An error if the operation fails
+func (c *NeatClient) CommitTransaction(transactionId int64) error {
+	return c.CommitTransactionCtx(context.Background(), transactionId)
+}
+
+// CommitTransactionCtx applies an uncommitted transaction to its cientity.
+// The request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the request
+//   - transactionId: The transaction ID
+//
+// Returns:
+//   - error: An error if the operation fails
+func (c *NeatClient) CommitTransactionCtx(ctx context.Context, transactionId int64) error {
+	return c.postJSON(ctx, "/api/rest/cmdb/transaction/commit", transactionIdRequest{TransactionId: transactionId}, nil)
+}
+
+// RecoverTransaction rolls a transaction back, restoring the cientity as it was before.
+//
+// Parameters:
+//   - transactionId: The transaction ID
+//
+// Returns:
+//   - error: An error if the operation fails
+func (c *NeatClient) RecoverTransaction(transactionId int64) error {
+	return c.RecoverTransactionCtx(context.Background(), transactionId)
+}
+
+// RecoverTransactionCtx rolls a transaction back, restoring the cientity as it was before.
+// The request is bound to ctx.
+//
+// Parameters:
+//   - ctx: Context controlling cancellation and deadline of the request
+//   - transactionId: The transaction ID
+//
+// Returns:
+//   - error: An error if the operation fails
+func (c *NeatClient) RecoverTransactionCtx(ctx context.Context, transactionId int64) error {
+	return c.postJSON(ctx, "/api/rest/cmdb/transaction/recover", transactionIdRequest{TransactionId: transactionId}, nil)
+}
+
+// attrChanges converts the attribute data of a transaction into changes ordered by attribute ID.
+func attrChanges(data map[string]interface{}) []AttrChange {
+	changes := make([]AttrChange, 0, len(data))
+	for key, value := range data {
+		entry, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		change := AttrChange{
+			Name:  toString(entry["name"]),
+			Label: toString(entry["label"]),
+		}
+		change.AttrId, _ = toInt64(entry["attrId"])
+		if change.AttrId == 0 {
+			change.AttrId = parseAttrKey(key)
+		}
+		change.OldValueList, _ = entry["oldValueList"].([]interface{})
+		change.NewValueList, _ = entry["valueList"].([]interface{})
+		change.OldActualValueList, _ = entry["oldActualValueList"].([]interface{})
+		change.NewActualValueList, _ = entry["actualValueList"].([]interface{})
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].AttrId < changes[j].AttrId
+	})
+	return changes
+}
+
+// parseAttrKey returns the attribute ID of an attrEntityData key such as attr_123, or zero.
+func parseAttrKey(key string) int64 {
+	id, ok := strings.CutPrefix(key, "attr_")
+	if !ok {
+		return 0
+	}
+	attrId, _ := strconv.ParseInt(id, 10, 64)
+	return attrId
+}
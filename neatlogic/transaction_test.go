@@ -0,0 +1,185 @@
+package neatlogic_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hejingwen098/neatapi/neatlogic"
+)
+
+// transactionServer answers the transaction APIs with canned responses and records the request bodies.
+type transactionServer struct {
+	*httptest.Server
+	// respond returns the response body for a request to the API endpoint with the given body.
+	respond func(endpoint string, body map[string]interface{}) string
+
+	mu     sync.Mutex
+	bodies map[string][]map[string]interface{}
+}
+
+// newTransactionServer starts a transactionServer and a client of it authenticated by token.
+func newTransactionServer(t *testing.T, respond func(endpoint string, body map[string]interface{}) string) (*transactionServer, *neatlogic.NeatClient) {
+	t.Helper()
+	srv := &transactionServer{respond: respond, bodies: map[string][]map[string]interface{}{}}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		endpoint := strings.TrimPrefix(r.URL.Path, "/demo/api/rest/cmdb/transaction/")
+		srv.mu.Lock()
+		srv.bodies[endpoint] = append(srv.bodies[endpoint], body)
+		srv.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(srv.respond(endpoint, body)))
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := neatlogic.New(
+		neatlogic.WithBaseURL(srv.URL),
+		neatlogic.WithTenant("demo"),
+		neatlogic.WithToken("token"),
+		neatlogic.WithHTTPClient(srv.Client()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+// sent returns the bodies of the requests to endpoint.
+func (s *transactionServer) sent(endpoint string) []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bodies[endpoint]
+}
+
+func TestListTransactions(t *testing.T) {
+	srv, client := newTransactionServer(t, func(endpoint string, body map[string]interface{}) string {
+		if body["currentPage"] == float64(1) {
+			return `{"Status":"OK","Return":{"pageCount":2,"tbodyList":[
+				{"id":1,"ciId":1,"ciEntityId":42,"ciEntityName":"web-01","action":"insert","status":"uncommit","createUser":"admin","createTime":1700000000000}
+			]}}`
+		}
+		return `{"Status":"OK","Return":{"pageCount":2,"tbodyList":[
+			{"id":2,"ciId":1,"ciEntityId":42,"ciEntityName":"web-01","action":"update","status":"uncommit"}
+		]}}`
+	})
+
+	start := time.UnixMilli(1700000000000)
+	end := time.UnixMilli(1700086400000)
+	transactions, err := client.ListTransactions(neatlogic.TransactionFilter{
+		CiId:       1,
+		CiEntityId: 42,
+		Status:     neatlogic.TransactionUncommitted,
+		Start:      start,
+		End:        end,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []neatlogic.Transaction{
+		{ID: 1, CiId: 1, CiEntityId: 42, CiEntityName: "web-01", Action: "insert", Status: neatlogic.TransactionUncommitted, CreateUser: "admin", CreateTime: 1700000000000},
+		{ID: 2, CiId: 1, CiEntityId: 42, CiEntityName: "web-01", Action: "update", Status: neatlogic.TransactionUncommitted},
+	}
+	if !reflect.DeepEqual(transactions, want) {
+		t.Errorf("transactions:\ngot  %+v\nwant %+v", transactions, want)
+	}
+	checkJSON(t, srv.sent("search"), `[
+		{"ciId": 1, "ciEntityId": 42, "status": "uncommit", "startTime": 1700000000000, "endTime": 1700086400000, "pageSize": 100, "currentPage": 1},
+		{"ciId": 1, "ciEntityId": 42, "status": "uncommit", "startTime": 1700000000000, "endTime": 1700086400000, "pageSize": 100, "currentPage": 2}
+	]`)
+}
+
+func TestListTransactionsWithoutFilter(t *testing.T) {
+	srv, client := newTransactionServer(t, func(string, map[string]interface{}) string {
+		return `{"Status":"OK","Return":{"pageCount":0,"tbodyList":[]}}`
+	})
+
+	transactions, err := client.ListTransactions(neatlogic.TransactionFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 0 {
+		t.Errorf("transactions = %+v, want none", transactions)
+	}
+	// Zero fields are left out instead of filtering on zero values
+	checkJSON(t, srv.sent("search"), `[{"pageSize": 100, "currentPage": 1}]`)
+}
+
+func TestGetTransaction(t *testing.T) {
+	srv, client := newTransactionServer(t, func(string, map[string]interface{}) string {
+		return `{"Status":"OK","Return":{
+			"id":7,"ciId":1,"ciEntityId":42,"ciEntityName":"web-01","action":"update","status":"commited",
+			"commitUser":"admin","commitTime":1700000000000,
+			"ciEntityTransactionVo":{"attrEntityData":{
+				"attr_12":{"name":"env","label":"Environment","valueList":[4],"actualValueList":["prod"],"oldValueList":[3],"oldActualValueList":["test"]},
+				"attr_3":{"attrId":3,"name":"hostname","label":"Host name","valueList":["web-02"],"oldValueList":["web-01"]},
+				"attr_9":{"name":"ip","label":"IP","valueList":["10.0.0.1"]}
+			}}
+		}}`
+	})
+
+	detail, err := client.GetTransaction(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkJSON(t, srv.sent("get"), `[{"transactionId": 7}]`)
+	wantTransaction := neatlogic.Transaction{
+		ID: 7, CiId: 1, CiEntityId: 42, CiEntityName: "web-01", Action: "update",
+		Status: neatlogic.TransactionCommitted, CommitUser: "admin", CommitTime: 1700000000000,
+	}
+	if detail.Transaction != wantTransaction {
+		t.Errorf("transaction:\ngot  %+v\nwant %+v", detail.Transaction, wantTransaction)
+	}
+	wantChanges := []neatlogic.AttrChange{
+		{AttrId: 3, Name: "hostname", Label: "Host name", OldValueList: []interface{}{"web-01"}, NewValueList: []interface{}{"web-02"}},
+		{AttrId: 9, Name: "ip", Label: "IP", NewValueList: []interface{}{"10.0.0.1"}},
+		{
+			AttrId: 12, Name: "env", Label: "Environment",
+			OldValueList: []interface{}{float64(3)}, NewValueList: []interface{}{float64(4)},
+			OldActualValueList: []interface{}{"test"}, NewActualValueList: []interface{}{"prod"},
+		},
+	}
+	if !reflect.DeepEqual(detail.AttrChanges, wantChanges) {
+		t.Errorf("attribute changes:\ngot  %+v\nwant %+v", detail.AttrChanges, wantChanges)
+	}
+}
+
+func TestCommitAndRecoverTransaction(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		call     func(*neatlogic.NeatClient, int64) error
+	}{
+		{"commit", (*neatlogic.NeatClient).CommitTransaction},
+		{"recover", (*neatlogic.NeatClient).RecoverTransaction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			srv, client := newTransactionServer(t, func(_ string, body map[string]interface{}) string {
+				if body["transactionId"] == float64(8) {
+					return `{"Status":"ERROR","Message":"transaction 8 has expired"}`
+				}
+				return `{"Status":"OK"}`
+			})
+
+			if err := tt.call(client, 7); err != nil {
+				t.Fatal(err)
+			}
+			err := tt.call(client, 8)
+			var apiErr *neatlogic.APIError
+			if !errors.As(err, &apiErr) || apiErr.Message != "transaction 8 has expired" {
+				t.Errorf("err = %v, want the ERROR message", err)
+			}
+			checkJSON(t, srv.sent(tt.endpoint), `[{"transactionId": 7}, {"transactionId": 8}]`)
+		})
+	}
+}